@@ -49,8 +49,39 @@ export type SearchRequest = {
 
 export type SearchResult = {
   score: number
+  rerankScore?: number
   user: User
-  description: string
+  assessment?: MatchAssessment
+  // set while the assessment streams and when it failed, not sent by the server
+  describing?: boolean
+  assessmentError?: string
+}
+
+export type ContactChannel =
+  | 'slack'
+  | 'telephone'
+  | 'email'
+  | 'telegram'
+  | 'whatsapp'
+
+export type MatchAssessment = {
+  fitScore: number
+  strengths: string[]
+  gaps: string[]
+  openingQuestion: string
+  contactChannel: ContactChannel
+}
+
+export type DescriptionDelta = {
+  userId: string
+  attempt: number
+  delta: string
+}
+
+export type DescriptionDone = {
+  userId: string
+  assessment?: MatchAssessment
+  error?: string
 }
 
 // #endregion
@@ -3,6 +3,7 @@ import { useCreateChat } from '@/common/hooks/useCreateChat'
 import { MainLayout } from '@/common/SidebarLayout'
 import type {
   CreateChatResponse,
+  DescriptionDone,
   GetChatResponse,
   SearchRequest,
   SearchResult,
@@ -19,6 +20,17 @@ import { useNavigate, useParams } from 'react-router-dom'
 import SearchForm from './SearchForm'
 import UserResultCard from './UserResultCard'
 
+// parseSSE splits a server-sent event into its name and data
+function parseSSE(raw: string): { event: string; data: string } {
+  let event = 'message'
+  const data: string[] = []
+  raw.split('\n').forEach((line) => {
+    if (line.startsWith('event:')) event = line.slice(6).trim()
+    else if (line.startsWith('data:')) data.push(line.slice(5).trim())
+  })
+  return { event, data: data.join('\n') }
+}
+
 const Chat: React.FC = () => {
   const [users, usersHandlers] = useListState<SearchResult>([])
   const access_token = useAuthStore((state) => state.accessToken)
@@ -74,6 +86,44 @@ const Chat: React.FC = () => {
     if (!chatId) handleClear()
   }, [handleClear, chatId])
 
+  const handleEvent = useCallback(
+    (raw: string): void => {
+      const { event, data } = parseSSE(raw)
+      if (!data) return
+
+      try {
+        switch (event) {
+          case 'result':
+            usersHandlers.append({
+              ...(JSON.parse(data) as SearchResult),
+              describing: true,
+            })
+            break
+          case 'description_done': {
+            const done = JSON.parse(data) as DescriptionDone
+            usersHandlers.applyWhere(
+              (res) => res.user?._id === done.userId,
+              (res) => ({
+                ...res,
+                assessment: done.assessment,
+                assessmentError: done.error,
+                describing: false,
+              }),
+            )
+            break
+          }
+          // description_delta streams the assessment JSON, the card waits for description_done.
+          // refinement, filters, facets and page are not shown yet
+          default:
+            break
+        }
+      } catch (err) {
+        console.warn('Failed to parse SSE:', raw, err)
+      }
+    },
+    [usersHandlers],
+  )
+
   const handleStream = useCallback(
     async (
       question: string,
@@ -110,33 +160,19 @@ const Chat: React.FC = () => {
 
         if (!response.body) throw new Error('No response body')
 
-        const stream = response.body
+        const reader = response.body
           .pipeThrough(new TextDecoderStream()) // превращает байты в строки
-          .pipeThrough(
-            new TransformStream({
-              transform(chunk, controller) {
-                chunk.split('\n\n').forEach((line) => controller.enqueue(line))
-              },
-            }),
-          )
-
-        const reader = stream.getReader()
+          .getReader()
         let buffer = ''
         while (true) {
           const { value, done } = await reader.read()
           if (done) break
-          const line = value.replace(/^event:result\s*/, '').trim()
-          if (!line || line === '[DONE]') continue
-
-          buffer += line.replace(/^data:\s*/, '')
+          buffer += value
 
-          try {
-            const parsed = JSON.parse(buffer)
-            usersHandlers.append(parsed)
-            buffer = ''
-          } catch (err) {
-            console.warn('Failed to parse SSE:', line, err)
-          }
+          // Events end with an empty line, the last one may still be incomplete
+          const events = buffer.split('\n\n')
+          buffer = events.pop() ?? ''
+          events.forEach(handleEvent)
         }
       } catch (err) {
         if (err instanceof DOMException && err.name === 'AbortError') {
@@ -148,11 +184,13 @@ const Chat: React.FC = () => {
           setError('Unknown error occurred')
         }
       } finally {
+        // A cancelled stream leaves assessments unfinished
+        usersHandlers.apply((res) => ({ ...res, describing: false }))
         setIsLoading(false)
         abortControllerRef.current = null
       }
     },
-    [access_token, handleClear, usersHandlers],
+    [access_token, handleClear, handleEvent, usersHandlers],
   )
 
   const handleCancel = useCallback((): void => {
@@ -1,11 +1,25 @@
-import { type SearchResult } from '@/common/types'
+import { type MatchAssessment, type SearchResult } from '@/common/types'
 import UserBadges from '@/common/UserBadges'
 import UserContacts from '@/common/UserContacts'
-import { Anchor, Group, Paper, Stack, Text, Title } from '@mantine/core'
+import { Anchor, Group, Loader, Paper, Stack, Text, Title } from '@mantine/core'
 import { IconHash } from '@tabler/icons-react'
 import React from 'react'
 import Interpretation from './Interpretation'
 
+// assessmentMarkdown renders the assessment with the markdown Interpretation understands
+function assessmentMarkdown(assessment: MatchAssessment): string {
+  const lines = [`**Fit:** ${Math.round(assessment.fitScore * 100)}%`]
+  if (assessment.strengths.length > 0) {
+    lines.push('### Strengths', ...assessment.strengths.map((s) => `- ${s}`))
+  }
+  if (assessment.gaps.length > 0) {
+    lines.push('### Gaps', ...assessment.gaps.map((g) => `- ${g}`))
+  }
+  lines.push(`> ${assessment.openingQuestion}`)
+  lines.push(`Reach out via **${assessment.contactChannel}**`)
+  return lines.join('\n')
+}
+
 type UserResultCardProps = {
   data: SearchResult
 }
@@ -64,7 +78,24 @@ function UserResultCard({ data }: UserResultCardProps) {
 
         <UserBadges user={user} />
 
-        <Interpretation interpretation={data.description} />
+        {data.assessment && (
+          <Interpretation
+            interpretation={assessmentMarkdown(data.assessment)}
+          />
+        )}
+        {data.describing && (
+          <Group gap="xs">
+            <Loader size="xs" />
+            <Text size="sm" c="dimmed">
+              Writing the assessment...
+            </Text>
+          </Group>
+        )}
+        {data.assessmentError && (
+          <Text size="sm" c="red">
+            {data.assessmentError}
+          </Text>
+        )}
         <UserContacts contact={user.contact} />
       </Stack>
     </Paper>
@@ -86,7 +86,14 @@ func startup(cfg *config.Config) {
 	redis := clients.ConnectToRedis(cfg)
 	defer redis.Close()
 
-	qdrantRepo := qdrant.New(cfg, vectorDb)
+	embeddingProvider, err := service.NewEmbeddingProvider(ctx, cfg.Embedder)
+	if err != nil {
+		telemetry.Log.Fatal("Failed to create embedding provider", zap.Error(err))
+	}
+	qdrantRepo, err := qdrant.New(cfg, vectorDb, embeddingProvider)
+	if err != nil {
+		telemetry.Log.Fatal("Embedding model does not fit Qdrant collections", zap.Error(err))
+	}
 
 	statusRepo := mongo.NewStatusRepository(db)
 	chatRepo := mongo.NewChatRepository(db)
@@ -102,17 +109,42 @@ func startup(cfg *config.Config) {
 		cfg.SMTP.From,
 		cfg.SMTP.FromName,
 	)
-	llmService := service.NewLLMService(cfg.OpenAIKey)
+	llmService, err := service.NewLLMService(cfg.LLM)
+	if err != nil {
+		telemetry.Log.Fatal("Failed to create LLM service", zap.Error(err))
+	}
 	chatService := service.NewChatService(chatRepo, userRepo)
 	authService := service.NewAuthService(userRepo)
-	embedderService := service.NewEmbedderService(cfg.Embedder.Url)
-	qdrantService := service.NewQdrantService(qdrantRepo, userRepo, embedderService)
-	organizationService := service.NewOrganizationService(orgRepo, userRepo, qdrantService)
+	embedderService := service.NewCachedEmbedder(ctx,
+		service.NewBatchingEmbedder(embeddingProvider, cfg.Embedder),
+		redis, cfg.Embedder)
+	qdrantService := service.NewQdrantService(qdrantRepo, userRepo, orgRepo, embedderService, cfg.Search)
+	if rekeyed, err := qdrantRepo.RekeyPoints(ctx); err != nil {
+		telemetry.Log.Error("Failed to rekey Qdrant points", zap.Error(err))
+	} else if rekeyed > 0 {
+		telemetry.Log.Info(fmt.Sprintf("Moved %d Qdrant points to UUID ids", rekeyed))
+	}
+	if err := qdrantRepo.CheckEmbeddingModel(ctx); errors.Is(err, qdrant.ErrModelMismatch) {
+		telemetry.Log.Error("Semantic search is disabled until users are reindexed or their model is confirmed", zap.Error(err))
+	} else if err != nil {
+		telemetry.Log.Error("Failed to check the embedding model of Qdrant collections", zap.Error(err))
+	}
+	if migrated, err := qdrantService.BackfillOrganizations(ctx); err != nil {
+		telemetry.Log.Error("Failed to backfill organization ids in Qdrant", zap.Error(err))
+	} else if migrated > 0 {
+		telemetry.Log.Info(fmt.Sprintf("Backfilled organization ids for %d Qdrant points", migrated))
+	}
+	outboxService := service.NewOutboxService(mongo.NewOutboxRepository(db), mongo.NewTransactor(ctx, db), userRepo, qdrantService, cfg.Outbox)
+	go outboxService.Run(ctx)
+	organizationService := service.NewOrganizationService(orgRepo, userRepo, qdrantService, cfg.LLM)
 	authMiddleware := jwtUtils.Startup(cfg, authService)
 	withAuth := jwtUtils.UseAuth(authMiddleware, cfg, redis)
 	logoutHandler := jwtUtils.LogoutHandler(authMiddleware, cfg, redis)
-	searchService := service.NewSearchService(qdrantService, llmService, orgRepo, chatRepo, userRepo, telemetry.Log)
-	userService := service.NewUserService(qdrantService, userRepo, orgRepo, emailService, authMiddleware, cfg)
+	if _, err := service.ParseRerankStrategy(cfg.Search.Rerank, service.RerankStrategies.None); err != nil {
+		telemetry.Log.Fatal("Invalid SEARCH_RERANK", zap.Error(err))
+	}
+	searchService := service.NewSearchService(qdrantService, llmService, embedderService, orgRepo, chatRepo, userRepo, telemetry.Log, cfg.Search)
+	userService := service.NewUserService(outboxService, userRepo, orgRepo, emailService, authMiddleware, cfg)
 
 	var googleAuthService routes.IGoogleAuthService
 	if cfg.Google.Enabled {
@@ -120,7 +152,7 @@ func startup(cfg *config.Config) {
 			cfg.Protocol+"://"+cfg.Host+":"+cfg.Port+"/api/v1"+routes.GoogleCallback,
 			cfg.Google.ClientID,
 			cfg.Google.ClientSecret)
-		googleAuthService = service.NewGoogleAuthService(qdrantService, userRepo, orgRepo, google, authMiddleware, cfg.FrontendUrl)
+		googleAuthService = service.NewGoogleAuthService(outboxService, userRepo, orgRepo, google, authMiddleware, cfg.FrontendUrl)
 	}
 	// endregion
 
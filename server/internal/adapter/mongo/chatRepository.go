@@ -19,6 +19,7 @@ type IChatRepository interface {
 	GetChatsByUserIDWithCursor(ctx context.Context, userID primitive.ObjectID, cursor string, limit int) ([]model.Chat, string, error)
 	DeleteChat(ctx context.Context, id primitive.ObjectID) error
 	AddChatMessages(ctx context.Context, chatId primitive.ObjectID, messages []model.Message) error
+	SetMessageAssessment(ctx context.Context, chatID primitive.ObjectID, userID string, assessment *model.MatchAssessment) error
 }
 
 type chatRepository struct {
@@ -153,4 +154,21 @@ func (r *chatRepository) AddChatMessages(ctx context.Context, chatID primitive.O
 	return nil
 }
 
+// SetMessageAssessment attaches the assessment to the message suggesting the user, a chat suggests a user once
+func (r *chatRepository) SetMessageAssessment(ctx context.Context, chatID primitive.ObjectID, userID string, assessment *model.MatchAssessment) error {
+	filter := bson.M{"_id": chatID, "messages.content.user": userID}
+	update := bson.M{"$set": bson.M{"messages.$.assessment": assessment}}
+	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Chats)
+	result, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("message of user %s not found", userID)
+	}
+
+	return nil
+}
+
 // endregion
@@ -39,6 +39,11 @@ func SetupMongo(cfg *config.MongoConfig) (*clients.MongoDb, error) {
 		return nil, err
 	}
 
+	if err := CreateOutboxCollection(db); err != nil {
+		telemetry.Log.Fatal("failed to create outbox collection", zap.Error(err))
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -57,6 +62,13 @@ func CreateUserCollection(db *clients.MongoDb) error {
 		return err
 	}
 
+	syncIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "syncToken", Value: 1}},
+		Options: options.Index().SetSparse(true)}
+	if _, err = coll.Indexes().CreateOne(ctx, syncIndexModel); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -96,3 +108,21 @@ func CreateOrganizationCollection(db *clients.MongoDb) error {
 
 	return nil
 }
+
+func CreateOutboxCollection(db *clients.MongoDb) error {
+	ctx := context.Background()
+	err := db.Client.Database(db.Database).CreateCollection(ctx, db.Collections.Outbox)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	coll := db.Client.Database(db.Database).Collection(db.Collections.Outbox)
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "available_at", Value: 1}},
+	}
+	if _, err = coll.Indexes().CreateOne(ctx, indexModel); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"semki/internal/model"
+	"semki/pkg/clients"
+	"time"
+)
+
+type IOutboxRepository interface {
+	Enqueue(ctx context.Context, userID primitive.ObjectID) error
+	EnqueueMissing(ctx context.Context, userID primitive.ObjectID) error
+	Claim(ctx context.Context, lease time.Duration) (*model.OutboxEvent, error)
+	Complete(ctx context.Context, id primitive.ObjectID) error
+	Retry(ctx context.Context, id primitive.ObjectID, availableAt time.Time, reason string) error
+	Fail(ctx context.Context, id primitive.ObjectID, reason string) error
+	Stats(ctx context.Context) (OutboxStats, error)
+}
+
+// OutboxStats - OldestPending is nil when the outbox is drained
+type OutboxStats struct {
+	Pending       int64
+	Failed        int64
+	OldestPending *time.Time
+}
+
+type outboxRepository struct {
+	client *clients.MongoDb
+}
+
+func NewOutboxRepository(client *clients.MongoDb) IOutboxRepository {
+	return &outboxRepository{client}
+}
+
+func (r *outboxRepository) collection() *mongo.Collection {
+	return r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Outbox)
+}
+
+// Enqueue joins the transaction of ctx, if any
+func (r *outboxRepository) Enqueue(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := r.collection().InsertOne(ctx, model.OutboxEvent{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Status:      model.OutboxStatuses.PENDING,
+		CreatedAt:   now,
+		AvailableAt: now,
+	})
+	return err
+}
+
+// EnqueueMissing adds an event unless the user already has a pending one
+func (r *outboxRepository) EnqueueMissing(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{"user_id": userID, "status": model.OutboxStatuses.PENDING}
+	update := bson.M{"$setOnInsert": bson.M{
+		"_id":          primitive.NewObjectID(),
+		"attempts":     0,
+		"created_at":   now,
+		"available_at": now,
+	}}
+	_, err := r.collection().UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// Claim takes the oldest due event and hides it from other workers for lease.
+// An event of a crashed worker becomes due again when the lease expires. Returns nil when nothing is due
+func (r *outboxRepository) Claim(ctx context.Context, lease time.Duration) (*model.OutboxEvent, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status":       model.OutboxStatuses.PENDING,
+		"available_at": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"available_at": now.Add(lease)},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "available_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var event model.OutboxEvent
+	err := r.collection().FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *outboxRepository) Complete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection().DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *outboxRepository) Retry(ctx context.Context, id primitive.ObjectID, availableAt time.Time, reason string) error {
+	update := bson.M{"$set": bson.M{"available_at": availableAt, "last_error": reason}}
+	_, err := r.collection().UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// Fail keeps the event for inspection, the worker does not pick it up anymore
+func (r *outboxRepository) Fail(ctx context.Context, id primitive.ObjectID, reason string) error {
+	update := bson.M{"$set": bson.M{"status": model.OutboxStatuses.FAILED, "last_error": reason}}
+	_, err := r.collection().UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *outboxRepository) Stats(ctx context.Context) (OutboxStats, error) {
+	var stats OutboxStats
+	coll := r.collection()
+
+	pending, err := coll.CountDocuments(ctx, bson.M{"status": model.OutboxStatuses.PENDING})
+	if err != nil {
+		return stats, err
+	}
+	stats.Pending = pending
+
+	failed, err := coll.CountDocuments(ctx, bson.M{"status": model.OutboxStatuses.FAILED})
+	if err != nil {
+		return stats, err
+	}
+	stats.Failed = failed
+
+	if pending == 0 {
+		return stats, nil
+	}
+	var oldest model.OutboxEvent
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	err = coll.FindOne(ctx, bson.M{"status": model.OutboxStatuses.PENDING}, opts).Decode(&oldest)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	stats.OldestPending = &oldest.CreatedAt
+	return stats, nil
+}
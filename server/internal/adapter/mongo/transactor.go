@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"semki/pkg/clients"
+	"semki/pkg/telemetry"
+)
+
+// ITransactor runs writes to several collections atomically
+type ITransactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	client    *clients.MongoDb
+	supported bool
+}
+
+// NewTransactor checks whether the deployment supports transactions. A standalone server does not,
+// there fn runs without a transaction and its writes are applied one by one.
+// The outbox does not depend on it, every user write also marks the user document for sync
+func NewTransactor(ctx context.Context, client *clients.MongoDb) ITransactor {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		telemetry.Log.Warn("Failed to detect MongoDB topology, transactions are disabled", zap.Error(err))
+	}
+
+	supported := hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !supported {
+		telemetry.Log.Warn("MongoDB is not a replica set, unsynced users are recovered from their sync token")
+	}
+	return &transactor{client, supported}
+}
+
+func (t *transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.supported {
+		return fn(ctx)
+	}
+
+	session, err := t.client.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
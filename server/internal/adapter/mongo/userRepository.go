@@ -11,6 +11,7 @@ import (
 	"semki/internal/utils/config"
 	"semki/internal/utils/crypto"
 	"semki/pkg/clients"
+	"time"
 )
 
 type IUserRepository interface {
@@ -23,6 +24,8 @@ type IUserRepository interface {
 	PatchUser(ctx context.Context, id primitive.ObjectID, data bson.M) error
 	DeleteUser(ctx context.Context, id primitive.ObjectID) error
 	RestoreUser(ctx context.Context, id primitive.ObjectID) error
+	GetUnsyncedUserIDs(ctx context.Context, before time.Time, limit int64) ([]primitive.ObjectID, error)
+	ClearSyncToken(ctx context.Context, id primitive.ObjectID, token primitive.ObjectID) error
 }
 
 type userRepository struct {
@@ -72,6 +75,7 @@ func (r *userRepository) CreateUser(ctx context.Context, user *model.User) error
 	if err != nil {
 		return err
 	}
+	encryptedUser.SyncToken = primitive.NewObjectID()
 	_, err = coll.InsertOne(ctx, encryptedUser)
 	return err
 }
@@ -96,13 +100,21 @@ func (r *userRepository) GetUserByID(ctx context.Context, id primitive.ObjectID)
 func (r *userRepository) UpdateUser(ctx context.Context, id primitive.ObjectID, user model.User) error {
 	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Users)
 	encryptedUser, err := crypto.EncryptUserFields(user, r.config.CryptoKey)
+	if err != nil {
+		return err
+	}
+	encryptedUser.SyncToken = primitive.NewObjectID()
 	_, err = coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": encryptedUser})
 	return err
 }
 
 func (r *userRepository) PatchUser(ctx context.Context, id primitive.ObjectID, update bson.M) error {
 	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Users)
-	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	set := bson.M{"syncToken": primitive.NewObjectID()}
+	for key, value := range update {
+		set[key] = value
+	}
+	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
 	return err
 }
 
@@ -110,7 +122,7 @@ func (r *userRepository) PatchUser(ctx context.Context, id primitive.ObjectID, u
 func (r *userRepository) DeleteUser(ctx context.Context, id primitive.ObjectID) error {
 	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Users)
 
-	update := bson.M{"$set": bson.M{"status": model.UserStatuses.DELETED}}
+	update := bson.M{"$set": bson.M{"status": model.UserStatuses.DELETED, "syncToken": primitive.NewObjectID()}}
 	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
@@ -119,7 +131,7 @@ func (r *userRepository) DeleteUser(ctx context.Context, id primitive.ObjectID)
 func (r *userRepository) RestoreUser(ctx context.Context, id primitive.ObjectID) error {
 	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Users)
 
-	update := bson.M{"$set": bson.M{"status": model.UserStatuses.ACTIVE}}
+	update := bson.M{"$set": bson.M{"status": model.UserStatuses.ACTIVE, "syncToken": primitive.NewObjectID()}}
 	_, err := coll.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
@@ -176,3 +188,44 @@ func (r *userRepository) GetUsersByOrganization(ctx context.Context, orgID primi
 }
 
 //endregion
+
+//region Sync
+
+// GetUnsyncedUserIDs - users whose last write is older than before and still not synced to Qdrant
+func (r *userRepository) GetUnsyncedUserIDs(ctx context.Context, before time.Time, limit int64) ([]primitive.ObjectID, error) {
+	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Users)
+
+	filter := bson.M{"syncToken": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(before)}}
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.M{"syncToken": 1}).
+		SetLimit(limit)
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+	return ids, cursor.Err()
+}
+
+// ClearSyncToken keeps the marker when the user was written again after token was read
+func (r *userRepository) ClearSyncToken(ctx context.Context, id primitive.ObjectID, token primitive.ObjectID) error {
+	coll := r.client.Client.Database(r.client.Database).Collection(r.client.Collections.Users)
+
+	filter := bson.M{"_id": id, "syncToken": token}
+	_, err := coll.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"syncToken": ""}})
+	return err
+}
+
+//endregion
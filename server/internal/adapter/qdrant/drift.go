@@ -0,0 +1,71 @@
+package qdrant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"semki/internal/model"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// ContentHashField - payload field with the hash of everything the point was built from
+const ContentHashField = "content_hash"
+
+// IndexedUser - user point without vectors, enough to tell whether it is up to date
+type IndexedUser struct {
+	UserID      string
+	ContentHash string
+}
+
+// UserContentHash changes whenever the user has to be re-embedded or its payload filters change
+func UserContentHash(user *model.User) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		user.OrganizationID.Hex(),
+		user.Semantic.Team.Hex(),
+		user.Semantic.Level.Hex(),
+		user.Semantic.Location.Hex(),
+		user.Semantic.Description,
+	}, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// ScrollIndexedUsers returns all user points of the organization. Points indexed before
+// content hashes were stored have an empty ContentHash
+func (r *repository) ScrollIndexedUsers(ctx context.Context, organizationID string) ([]IndexedUser, error) {
+	users := make([]IndexedUser, 0)
+	var offset *qdrant.PointId
+	for {
+		resp, err := r.client.Points.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: r.collectionName,
+			Filter: &qdrant.Filter{
+				Must: []*qdrant.Condition{qdrant.NewMatchKeyword("organization_id", organizationID)},
+			},
+			Offset:      offset,
+			Limit:       qdrant.PtrOf(uint32(copyBatchSize)),
+			WithPayload: qdrant.NewWithPayloadInclude("user_id", ContentHashField),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll users: %w", err)
+		}
+
+		for _, point := range resp.Result {
+			userID, err := r.payloadToUser(point.Payload)
+			if err != nil {
+				return nil, err
+			}
+			users = append(users, IndexedUser{
+				UserID:      userID,
+				ContentHash: point.Payload[ContentHashField].GetStringValue(),
+			})
+		}
+
+		if resp.NextPageOffset == nil {
+			return users, nil
+		}
+		offset = resp.NextPageOffset
+	}
+}
@@ -0,0 +1,282 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const (
+	// EmbeddingModelField and EmbeddingVersionField stamp every point with the model that built its vector
+	EmbeddingModelField   = "embedding_model"
+	EmbeddingVersionField = "embedding_version"
+	// UnknownModel stamps legacy points of a collection whose dimensions don't fit the configured model.
+	// They count as another model until an operator confirms they were built with the configured one
+	UnknownModel = "unknown"
+	// maxModelFacets - distinct models or versions reported per collection
+	maxModelFacets = 100
+)
+
+// ErrModelMismatch - the live collections hold vectors the configured model can't be compared with
+var ErrModelMismatch = errors.New("collection holds vectors of another embedding model")
+
+// ModelPoints - points built with one model version, Model is empty for points without a stamp
+type ModelPoints struct {
+	Model   string
+	Version string
+	Points  int
+}
+
+// EmbeddingStats - vectors of the live collections by the model that built them.
+// Model, Version and Dimensions are the configured ones, Mismatch is why dense search is disabled
+type EmbeddingStats struct {
+	Model      string
+	Version    string
+	Dimensions int
+	Users      []ModelPoints
+	Passages   []ModelPoints
+	Mismatch   error
+}
+
+// modelState - why the live version can't be searched with the configured model, nil when it can
+type modelState struct {
+	mismatch error
+}
+
+// stampModel adds the configured model to a point payload
+func (r *repository) stampModel(payload map[string]*qdrant.Value) {
+	payload[EmbeddingModelField] = qdrant.NewValueString(r.modelID)
+	payload[EmbeddingVersionField] = qdrant.NewValueString(r.modelVersion)
+}
+
+// modelFilter matches points built with the configured model
+func (r *repository) modelFilter() *qdrant.Filter {
+	return &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatchKeyword(EmbeddingModelField, r.modelID),
+			qdrant.NewMatchKeyword(EmbeddingVersionField, r.modelVersion),
+		},
+	}
+}
+
+// denseSearchable refuses dense search while the live vectors come from another model, lexical search stays available
+func (r *repository) denseSearchable() error {
+	r.versions.mu.RLock()
+	defer r.versions.mu.RUnlock()
+	return r.versions.model.mismatch
+}
+
+func (r *repository) setModelMismatch(err error) {
+	r.versions.mu.Lock()
+	defer r.versions.mu.Unlock()
+	r.versions.model.mismatch = err
+}
+
+// CheckEmbeddingModel compares the live collections with the configured model and disables dense
+// search on a mismatch. Points written before they were stamped are stamped with the configured model
+// when the dimensions fit, models used to be swapped only together with a reindex, otherwise as UnknownModel
+func (r *repository) CheckEmbeddingModel(ctx context.Context) error {
+	live := CollectionVersion{Users: r.collectionName, Passages: r.passagesCollection}
+	if err := r.stampLegacyPoints(ctx, live); err != nil {
+		return err
+	}
+	err := r.checkVersionModel(ctx, live)
+	if err == nil || errors.Is(err, ErrModelMismatch) {
+		r.setModelMismatch(err)
+	}
+	return err
+}
+
+// stampLegacyPoints stamps points written before they were stamped, see CheckEmbeddingModel
+func (r *repository) stampLegacyPoints(ctx context.Context, version CollectionVersion) error {
+	for _, collection := range []string{version.Users, version.Passages} {
+		model, modelVersion := r.modelID, r.modelVersion
+		if err := r.checkVectorSize(ctx, collection); errors.Is(err, ErrModelMismatch) {
+			model, modelVersion = UnknownModel, UnknownModel
+		} else if err != nil {
+			return err
+		}
+
+		_, err := r.client.Points.SetPayload(ctx, &qdrant.SetPayloadPoints{
+			CollectionName: collection,
+			Wait:           qdrant.PtrOf(true),
+			Payload: map[string]*qdrant.Value{
+				EmbeddingModelField:   qdrant.NewValueString(model),
+				EmbeddingVersionField: qdrant.NewValueString(modelVersion),
+			},
+			PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+				Must: []*qdrant.Condition{qdrant.NewIsEmpty(EmbeddingModelField)},
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stamp points of %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// ConfirmLegacyModel restamps the live UnknownModel points with the configured model once an operator
+// confirmed they were built with it, and enables dense search unless points of other models remain.
+// Returns the number of confirmed user points
+func (r *repository) ConfirmLegacyModel(ctx context.Context) (int, error) {
+	if !r.versions.build.TryLock() {
+		return 0, ErrBuildInProgress
+	}
+	defer r.versions.build.Unlock()
+
+	live := CollectionVersion{Users: r.collectionName, Passages: r.passagesCollection}
+	unknown := &qdrant.Filter{Must: []*qdrant.Condition{qdrant.NewMatchKeyword(EmbeddingModelField, UnknownModel)}}
+	confirmed := 0
+	for _, collection := range []string{live.Users, live.Passages} {
+		if err := r.checkVectorSize(ctx, collection); err != nil {
+			return confirmed, err
+		}
+		if collection == live.Users {
+			count, err := r.client.Points.Count(ctx, &qdrant.CountPoints{
+				CollectionName: collection,
+				Filter:         unknown,
+				Exact:          qdrant.PtrOf(true),
+			})
+			if err != nil {
+				return confirmed, fmt.Errorf("failed to count points of %s: %w", collection, err)
+			}
+			confirmed = int(count.GetResult().GetCount())
+		}
+
+		_, err := r.client.Points.SetPayload(ctx, &qdrant.SetPayloadPoints{
+			CollectionName: collection,
+			Wait:           qdrant.PtrOf(true),
+			Payload: map[string]*qdrant.Value{
+				EmbeddingModelField:   qdrant.NewValueString(r.modelID),
+				EmbeddingVersionField: qdrant.NewValueString(r.modelVersion),
+			},
+			PointsSelector: qdrant.NewPointsSelectorFilter(unknown),
+		})
+		if err != nil {
+			return confirmed, fmt.Errorf("failed to confirm points of %s: %w", collection, err)
+		}
+	}
+
+	err := r.checkVersionModel(ctx, live)
+	if err != nil && !errors.Is(err, ErrModelMismatch) {
+		return confirmed, err
+	}
+	r.setModelMismatch(err)
+	return confirmed, nil
+}
+
+// checkVersionModel returns ErrModelMismatch when any vector of the version comes from another model
+func (r *repository) checkVersionModel(ctx context.Context, version CollectionVersion) error {
+	for _, collection := range []string{version.Users, version.Passages} {
+		if err := r.checkVectorSize(ctx, collection); err != nil {
+			return err
+		}
+
+		others, err := r.client.Points.Count(ctx, &qdrant.CountPoints{
+			CollectionName: collection,
+			Filter:         &qdrant.Filter{MustNot: []*qdrant.Condition{qdrant.NewFilterAsCondition(r.modelFilter())}},
+			Exact:          qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count points of %s: %w", collection, err)
+		}
+		if count := others.GetResult().GetCount(); count > 0 {
+			return fmt.Errorf("%w: %d points of %s were not built with %s@%s",
+				ErrModelMismatch, count, collection, r.modelID, r.modelVersion)
+		}
+	}
+	return nil
+}
+
+func (r *repository) checkVectorSize(ctx context.Context, collection string) error {
+	info, err := r.client.Collections.Get(ctx, &qdrant.GetCollectionInfoRequest{CollectionName: collection})
+	if err != nil {
+		return fmt.Errorf("failed to get collection info of %s: %w", collection, err)
+	}
+	if size := info.GetResult().GetConfig().GetParams().GetVectorsConfig().GetParams().GetSize(); size != r.vectorSize {
+		return fmt.Errorf("%w: %s stores %d dimensions, %s@%s returns %d",
+			ErrModelMismatch, collection, size, r.modelID, r.modelVersion, r.vectorSize)
+	}
+	return nil
+}
+
+// EmbeddingStats counts live points of the organization per model version
+func (r *repository) EmbeddingStats(ctx context.Context, organizationID string) (EmbeddingStats, error) {
+	if organizationID == "" {
+		return EmbeddingStats{}, ErrMissingOrganization
+	}
+
+	users, err := r.countByModel(ctx, r.collectionName, organizationID)
+	if err != nil {
+		return EmbeddingStats{}, err
+	}
+	passages, err := r.countByModel(ctx, r.passagesCollection, organizationID)
+	if err != nil {
+		return EmbeddingStats{}, err
+	}
+	return EmbeddingStats{
+		Model:      r.modelID,
+		Version:    r.modelVersion,
+		Dimensions: int(r.vectorSize),
+		Users:      users,
+		Passages:   passages,
+		Mismatch:   r.denseSearchable(),
+	}, nil
+}
+
+func (r *repository) countByModel(ctx context.Context, collection string, organizationID string) ([]ModelPoints, error) {
+	tenant := qdrant.NewMatchKeyword("organization_id", organizationID)
+	models, err := r.facet(ctx, collection, EmbeddingModelField, &qdrant.Filter{Must: []*qdrant.Condition{tenant}})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]ModelPoints, 0)
+	for _, model := range models {
+		versions, err := r.facet(ctx, collection, EmbeddingVersionField, &qdrant.Filter{
+			Must: []*qdrant.Condition{tenant, qdrant.NewMatchKeyword(EmbeddingModelField, model.GetValue().GetStringValue())},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			counts = append(counts, ModelPoints{
+				Model:   model.GetValue().GetStringValue(),
+				Version: version.GetValue().GetStringValue(),
+				Points:  int(version.GetCount()),
+			})
+		}
+	}
+
+	unstamped, err := r.client.Points.Count(ctx, &qdrant.CountPoints{
+		CollectionName: collection,
+		Filter:         &qdrant.Filter{Must: []*qdrant.Condition{tenant, qdrant.NewIsEmpty(EmbeddingModelField)}},
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count points of %s: %w", collection, err)
+	}
+	if count := unstamped.GetResult().GetCount(); count > 0 {
+		counts = append(counts, ModelPoints{Points: int(count)})
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Points > counts[j].Points })
+	return counts, nil
+}
+
+func (r *repository) facet(ctx context.Context, collection string, key string, filter *qdrant.Filter) ([]*qdrant.FacetHit, error) {
+	resp, err := r.client.Points.Facet(ctx, &qdrant.FacetCounts{
+		CollectionName: collection,
+		Key:            key,
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(uint64(maxModelFacets)),
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s of %s: %w", key, collection, err)
+	}
+	return resp.GetHits(), nil
+}
@@ -0,0 +1,69 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+
+	"semki/pkg/lib"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// FacetCounts - number of candidates per team, level and location id
+type FacetCounts struct {
+	Teams     map[string]int
+	Levels    map[string]int
+	Locations map[string]int
+}
+
+// CountFacets counts candidates scoring at least threshold against the dense vector.
+// Without a vector the lexical query is used and every lexical match counts
+func (r *repository) CountFacets(ctx context.Context, vector []float32, filters SearchFilters, threshold float32) (FacetCounts, error) {
+	filter, err := buildSearchFilter(filters)
+	if err != nil {
+		return FacetCounts{}, err
+	}
+
+	request := &qdrant.QueryPoints{
+		CollectionName: r.collectionName,
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(filters.Limit),
+		WithPayload:    qdrant.NewWithPayloadInclude("team", "level", "location"),
+	}
+	if len(vector) > 0 {
+		if err := r.denseSearchable(); err != nil {
+			return FacetCounts{}, err
+		}
+		request.Query = qdrant.NewQueryDense(vector)
+		request.Using = qdrant.PtrOf(DenseVector)
+		request.ScoreThreshold = qdrant.PtrOf(threshold)
+	} else {
+		lexical := lib.SparseQueryVector(filters.Query)
+		if len(lexical.Indices) == 0 {
+			return newFacetCounts(), nil
+		}
+		request.Query = qdrant.NewQuerySparse(lexical.Indices, lexical.Values)
+		request.Using = qdrant.PtrOf(LexicalVector)
+	}
+
+	resp, err := r.client.Points.Query(ctx, request)
+	if err != nil {
+		return FacetCounts{}, fmt.Errorf("failed to count facets: %w", err)
+	}
+
+	counts := newFacetCounts()
+	for _, point := range resp.Result {
+		counts.Teams[point.Payload["team"].GetStringValue()]++
+		counts.Levels[point.Payload["level"].GetStringValue()]++
+		counts.Locations[point.Payload["location"].GetStringValue()]++
+	}
+	return counts, nil
+}
+
+func newFacetCounts() FacetCounts {
+	return FacetCounts{
+		Teams:     map[string]int{},
+		Levels:    map[string]int{},
+		Locations: map[string]int{},
+	}
+}
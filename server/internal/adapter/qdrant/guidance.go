@@ -0,0 +1,155 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// GuidanceCollection - embeddings of team and level descriptions ("Ask them about ... Do not ask ...")
+const GuidanceCollection = "guidance"
+
+//region GuidanceKind
+
+type GuidanceKind string
+
+var GuidanceKinds = struct {
+	Team  GuidanceKind
+	Level GuidanceKind
+}{
+	Team:  "team",
+	Level: "level",
+}
+
+//endregion
+
+type GuidanceVector struct {
+	Kind   GuidanceKind
+	RefID  string
+	Vector []float32
+}
+
+// GuidanceScores - query similarity to every team and level of the organization, keyed by team/level id
+type GuidanceScores map[string]float32
+
+func (r *repository) initializeGuidanceCollection(ctx context.Context) error {
+	exists, err := r.client.Collections.CollectionExists(ctx, &qdrant.CollectionExistsRequest{CollectionName: GuidanceCollection})
+	if err != nil {
+		return fmt.Errorf("failed to check guidance collection: %w", err)
+	}
+
+	if !exists.GetResult().GetExists() {
+		_, err = r.client.Collections.Create(ctx, &qdrant.CreateCollection{
+			CollectionName: GuidanceCollection,
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+				Size:     r.vectorSize,
+				Distance: qdrant.Distance_Cosine,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create guidance collection: %w", err)
+		}
+	}
+
+	fieldType := qdrant.FieldType_FieldTypeKeyword
+	_, err = r.client.Points.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+		CollectionName: GuidanceCollection,
+		FieldName:      "organization_id",
+		FieldType:      &fieldType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index for organization_id: %w", err)
+	}
+
+	return nil
+}
+
+// IndexGuidance replaces all team and level guidance of the organization
+func (r *repository) IndexGuidance(ctx context.Context, organizationID string, guidance []GuidanceVector) error {
+	_, err := r.client.Points.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: GuidanceCollection,
+		Wait:           qdrant.PtrOf(true),
+		Points: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{qdrant.NewMatchKeyword("organization_id", organizationID)},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete guidance: %w", err)
+	}
+
+	if len(guidance) == 0 {
+		return nil
+	}
+
+	points := make([]*qdrant.PointStruct, 0, len(guidance))
+	for _, g := range guidance {
+		pointID, err := r.userIDToPointID(g.RefID)
+		if err != nil {
+			return fmt.Errorf("failed to convert guidance ID: %w", err)
+		}
+		payload := map[string]*qdrant.Value{
+			"organization_id": qdrant.NewValueString(organizationID),
+			"kind":            qdrant.NewValueString(string(g.Kind)),
+			"ref_id":          qdrant.NewValueString(g.RefID),
+		}
+		r.stampModel(payload)
+		points = append(points, &qdrant.PointStruct{
+			Id:      pointID,
+			Vectors: qdrant.NewVectorsDense(g.Vector),
+			Payload: payload,
+		})
+	}
+
+	_, err = r.client.Points.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: GuidanceCollection,
+		Points:         points,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index guidance: %w", err)
+	}
+
+	return nil
+}
+
+// SearchGuidance scores the query against every team and level guidance of the organization
+func (r *repository) SearchGuidance(ctx context.Context, organizationID string, vector []float32) (GuidanceScores, error) {
+	if organizationID == "" {
+		return nil, ErrMissingOrganization
+	}
+
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{qdrant.NewMatchKeyword("organization_id", organizationID)},
+	}
+
+	count, err := r.client.Points.Count(ctx, &qdrant.CountPoints{
+		CollectionName: GuidanceCollection,
+		Filter:         filter,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count guidance: %w", err)
+	}
+
+	scores := GuidanceScores{}
+	if count.GetResult().GetCount() == 0 {
+		return scores, nil
+	}
+
+	resp, err := r.client.Points.Search(ctx, &qdrant.SearchPoints{
+		CollectionName: GuidanceCollection,
+		Vector:         vector,
+		Limit:          count.GetResult().GetCount(),
+		Filter:         filter,
+		WithPayload:    qdrant.NewWithPayloadInclude("ref_id"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search guidance: %w", err)
+	}
+
+	for _, point := range resp.Result {
+		scores[point.Payload["ref_id"].GetStringValue()] = point.Score
+	}
+
+	return scores, nil
+}
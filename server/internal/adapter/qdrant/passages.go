@@ -0,0 +1,207 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+
+	"semki/internal/model"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// PassagesCollection - description passages, one point per passage, tied to the user by user_id.
+// Base name of the versioned collections, read and written through PassagesAlias
+const PassagesCollection = "user_passages"
+
+//region PassageAggregation
+
+// PassageAggregation - how passage similarities make up the user score
+type PassageAggregation string
+
+var PassageAggregations = struct {
+	None PassageAggregation
+	Max  PassageAggregation
+	Sum  PassageAggregation
+}{
+	None: "none",
+	Max:  "max",
+	Sum:  "sum",
+}
+
+func ParsePassageAggregation(aggregation string) (PassageAggregation, error) {
+	switch PassageAggregation(aggregation) {
+	case PassageAggregations.None, PassageAggregations.Max, PassageAggregations.Sum:
+		return PassageAggregation(aggregation), nil
+	default:
+		return "", fmt.Errorf("unknown passage aggregation %q", aggregation)
+	}
+}
+
+//endregion
+
+type Passage struct {
+	Index  int
+	Text   string
+	Vector []float32
+}
+
+// PassageMatch - passage of the user description that matched the query
+type PassageMatch struct {
+	Index int
+	Text  string
+	Score float32
+}
+
+type PassageHit struct {
+	PassageMatch
+	UserID   string
+	Team     string
+	Level    string
+	Location string
+}
+
+func (r *repository) createPassagesCollection(ctx context.Context, name string) error {
+	exists, err := r.client.Collections.CollectionExists(ctx, &qdrant.CollectionExistsRequest{CollectionName: name})
+	if err != nil {
+		return fmt.Errorf("failed to check passages collection: %w", err)
+	}
+	if exists.GetResult().GetExists() {
+		return nil
+	}
+
+	_, err = r.client.Collections.Create(ctx, &qdrant.CreateCollection{
+		CollectionName: name,
+		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+			Size:     r.vectorSize,
+			Distance: qdrant.Distance_Cosine,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create passages collection: %w", err)
+	}
+
+	return r.createPassageIndexes(ctx, name)
+}
+
+func (r *repository) createPassageIndexes(ctx context.Context, name string) error {
+	fieldType := qdrant.FieldType_FieldTypeKeyword
+	for _, field := range []string{"user_id", "organization_id", "team", "level", "location", EmbeddingModelField, EmbeddingVersionField} {
+		_, err := r.client.Points.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: name,
+			FieldName:      field,
+			FieldType:      &fieldType,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create passages index for %s: %w", field, err)
+		}
+	}
+
+	return nil
+}
+
+// IndexUserPassages replaces all passages of the user
+func (r *repository) IndexUserPassages(ctx context.Context, user *model.User, passages []Passage) error {
+	if err := r.deleteUserPassages(ctx, user.ID.Hex()); err != nil {
+		return err
+	}
+
+	if len(passages) == 0 {
+		return nil
+	}
+
+	payload, err := r.userToPayload(user)
+	if err != nil {
+		return fmt.Errorf("failed to create payload: %w", err)
+	}
+
+	points := make([]*qdrant.PointStruct, 0, len(passages))
+	for _, p := range passages {
+		pointID, err := passagePointID(user.ID.Hex(), p.Index)
+		if err != nil {
+			return fmt.Errorf("failed to convert passage ID: %w", err)
+		}
+
+		passagePayload := make(map[string]*qdrant.Value, len(payload)+2)
+		for k, v := range payload {
+			passagePayload[k] = v
+		}
+		passagePayload["passage_index"] = qdrant.NewValueInt(int64(p.Index))
+		passagePayload["text"] = qdrant.NewValueString(p.Text)
+
+		points = append(points, &qdrant.PointStruct{
+			Id:      pointID,
+			Vectors: qdrant.NewVectorsDense(p.Vector),
+			Payload: passagePayload,
+		})
+	}
+
+	for _, collection := range r.passageCollections() {
+		_, err = r.client.Points.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: collection,
+			Points:         points,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to index passages: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *repository) deleteUserPassages(ctx context.Context, userID string) error {
+	for _, collection := range r.passageCollections() {
+		_, err := r.client.Points.Delete(ctx, &qdrant.DeletePoints{
+			CollectionName: collection,
+			Wait:           qdrant.PtrOf(true),
+			Points: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+				Must: []*qdrant.Condition{qdrant.NewMatchKeyword("user_id", userID)},
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete passages: %w", err)
+		}
+	}
+	return nil
+}
+
+// SearchPassages returns the best passages for the query, optionally only of the given users.
+// filters.Limit limits passages, not users
+func (r *repository) SearchPassages(ctx context.Context, vector []float32, filters SearchFilters, userIDs []string) ([]PassageHit, error) {
+	if err := r.denseSearchable(); err != nil {
+		return nil, err
+	}
+	filter, err := buildSearchFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(userIDs) > 0 {
+		filter.Must = append(filter.Must, qdrant.NewMatchKeywords("user_id", userIDs...))
+	}
+
+	resp, err := r.client.Points.Search(ctx, &qdrant.SearchPoints{
+		CollectionName: r.passagesCollection,
+		Vector:         vector,
+		Limit:          filters.Limit,
+		Filter:         filter,
+		WithPayload:    qdrant.NewWithPayload(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search passages: %w", err)
+	}
+
+	hits := make([]PassageHit, 0, len(resp.Result))
+	for _, point := range resp.Result {
+		hits = append(hits, PassageHit{
+			PassageMatch: PassageMatch{
+				Index: int(point.Payload["passage_index"].GetIntegerValue()),
+				Text:  point.Payload["text"].GetStringValue(),
+				Score: point.Score,
+			},
+			UserID:   point.Payload["user_id"].GetStringValue(),
+			Team:     point.Payload["team"].GetStringValue(),
+			Level:    point.Payload["level"].GetStringValue(),
+			Location: point.Payload["location"].GetStringValue(),
+		})
+	}
+	return hits, nil
+}
@@ -0,0 +1,150 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/qdrant/go-client/qdrant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrPointCollision - the point id is owned by another user, upserting would overwrite its vectors
+var ErrPointCollision = errors.New("point id belongs to another user")
+
+// objectPointID is a UUID carrying all 12 bytes of the ObjectID, so different ids never collide.
+// sub tells apart several points derived from one ObjectID, e.g. passages of a user
+func objectPointID(hexID string, sub uint16) (*qdrant.PointId, error) {
+	oid, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return nil, err
+	}
+
+	var id uuid.UUID
+	copy(id[0:6], oid[0:6])
+	id[6] = 0x80 // version 8, custom layout
+	id[7] = byte(sub >> 8)
+	id[8] = 0x80 // RFC 4122 variant
+	id[9] = byte(sub)
+	copy(id[10:16], oid[6:12])
+	return qdrant.NewIDUUID(id.String()), nil
+}
+
+func (r *repository) userIDToPointID(userID string) (*qdrant.PointId, error) {
+	return objectPointID(userID, 0)
+}
+
+func passagePointID(userID string, index int) (*qdrant.PointId, error) {
+	if index < 0 || index > 0xffff {
+		return nil, fmt.Errorf("passage index %d out of range", index)
+	}
+	return objectPointID(userID, uint16(index))
+}
+
+// checkPointOwner refuses to overwrite a point that stores another user
+func (r *repository) checkPointOwner(ctx context.Context, collection string, pointID *qdrant.PointId, userID string) error {
+	points, err := r.client.Points.Get(ctx, &qdrant.GetPoints{
+		CollectionName: collection,
+		Ids:            []*qdrant.PointId{pointID},
+		WithPayload:    qdrant.NewWithPayloadInclude("user_id"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get point: %w", err)
+	}
+	for _, point := range points.GetResult() {
+		if owner := point.Payload["user_id"].GetStringValue(); owner != "" && owner != userID {
+			return fmt.Errorf("%w: %s is owned by %s, not %s", ErrPointCollision, pointID.GetUuid(), owner, userID)
+		}
+	}
+	return nil
+}
+
+// RekeyPoints moves points with numeric ids of the live version and guidance to UUID ids
+func (r *repository) RekeyPoints(ctx context.Context) (int, error) {
+	rekeyed, err := r.rekeyVersion(ctx, CollectionVersion{Users: r.collectionName, Passages: r.passagesCollection})
+	if err != nil {
+		return rekeyed, err
+	}
+
+	guidance, err := r.rekeyCollection(ctx, GuidanceCollection, func(payload map[string]*qdrant.Value) (*qdrant.PointId, error) {
+		return objectPointID(payload["ref_id"].GetStringValue(), 0)
+	})
+	return rekeyed + guidance, err
+}
+
+func (r *repository) rekeyVersion(ctx context.Context, version CollectionVersion) (int, error) {
+	users, err := r.rekeyCollection(ctx, version.Users, func(payload map[string]*qdrant.Value) (*qdrant.PointId, error) {
+		return r.userIDToPointID(payload["user_id"].GetStringValue())
+	})
+	if err != nil {
+		return users, err
+	}
+
+	passages, err := r.rekeyCollection(ctx, version.Passages, func(payload map[string]*qdrant.Value) (*qdrant.PointId, error) {
+		return passagePointID(payload["user_id"].GetStringValue(), int(payload["passage_index"].GetIntegerValue()))
+	})
+	return users + passages, err
+}
+
+// rekeyCollection re-inserts every point with a numeric id under the id computed from its payload.
+// Points are upserted before the old ids are deleted, so an interrupted run loses nothing and can be repeated
+func (r *repository) rekeyCollection(ctx context.Context, collection string, pointID func(payload map[string]*qdrant.Value) (*qdrant.PointId, error)) (int, error) {
+	rekeyed := 0
+	var offset *qdrant.PointId
+	for {
+		resp, err := r.client.Points.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: collection,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(copyBatchSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return rekeyed, fmt.Errorf("failed to scroll %s: %w", collection, err)
+		}
+
+		points := make([]*qdrant.PointStruct, 0)
+		oldIDs := make([]*qdrant.PointId, 0)
+		for _, point := range resp.Result {
+			if point.Id.GetUuid() != "" {
+				continue
+			}
+			id, err := pointID(point.Payload)
+			if err != nil {
+				return rekeyed, fmt.Errorf("failed to rekey point %d of %s: %w", point.Id.GetNum(), collection, err)
+			}
+			points = append(points, &qdrant.PointStruct{
+				Id:      id,
+				Vectors: vectorsOutputToInput(point.Vectors),
+				Payload: point.Payload,
+			})
+			oldIDs = append(oldIDs, point.Id)
+		}
+
+		if len(points) > 0 {
+			_, err = r.client.Points.Upsert(ctx, &qdrant.UpsertPoints{
+				CollectionName: collection,
+				Wait:           qdrant.PtrOf(true),
+				Points:         points,
+			})
+			if err != nil {
+				return rekeyed, fmt.Errorf("failed to upsert rekeyed points of %s: %w", collection, err)
+			}
+			_, err = r.client.Points.Delete(ctx, &qdrant.DeletePoints{
+				CollectionName: collection,
+				Wait:           qdrant.PtrOf(true),
+				Points:         qdrant.NewPointsSelector(oldIDs...),
+			})
+			if err != nil {
+				return rekeyed, fmt.Errorf("failed to delete numeric points of %s: %w", collection, err)
+			}
+			rekeyed += len(points)
+		}
+
+		if resp.NextPageOffset == nil {
+			return rekeyed, nil
+		}
+		offset = resp.NextPageOffset
+	}
+}
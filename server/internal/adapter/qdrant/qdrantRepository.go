@@ -2,29 +2,99 @@ package qdrant
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"semki/internal/model"
 	"semki/internal/utils/config"
 	"semki/pkg/clients"
+	"semki/pkg/lib"
 
 	"github.com/qdrant/go-client/qdrant"
 )
 
-const UsersCollection = "users"
+const (
+	// UsersCollection - base name of the versioned users collections, read and written through UsersAlias
+	UsersCollection = "users"
+	// DenseVector - default unnamed vector with the description embedding
+	DenseVector = ""
+	// LexicalVector - sparse BM25-style vector of the description
+	LexicalVector = "description_bm25"
+)
+
+//region SearchMode
+
+type SearchMode string
+
+var SearchModes = struct {
+	Semantic SearchMode
+	Lexical  SearchMode
+	Hybrid   SearchMode
+}{
+	Semantic: "semantic",
+	Lexical:  "lexical",
+	Hybrid:   "hybrid",
+}
+
+func ParseSearchMode(mode string) (SearchMode, error) {
+	switch SearchMode(mode) {
+	case "":
+		return SearchModes.Semantic, nil
+	case SearchModes.Semantic, SearchModes.Lexical, SearchModes.Hybrid:
+		return SearchMode(mode), nil
+	}
+	return "", fmt.Errorf("unknown search mode: %s", mode)
+}
+
+//endregion
+
+// ErrMissingOrganization - every search must be scoped to a tenant
+var ErrMissingOrganization = errors.New("organization id is required")
 
 type SearchFilters struct {
-	Query     string   `json:"query"`
-	Teams     []string `json:"teams"`
-	Levels    []string `json:"levels"`
-	Locations []string `json:"locations"`
-	Limit     uint64   `json:"limit"`
+	OrganizationID string     `json:"organization_id"`
+	Query          string     `json:"query"`
+	Mode           SearchMode `json:"mode"`
+	Teams          []string   `json:"teams"`
+	Levels         []string   `json:"levels"`
+	Locations      []string   `json:"locations"`
+	Limit          uint64     `json:"limit"`
+	// Exclude* are must_not conditions
+	ExcludeTeams     []string `json:"exclude_teams"`
+	ExcludeLevels    []string `json:"exclude_levels"`
+	ExcludeLocations []string `json:"exclude_locations"`
+	ExcludeUserIDs   []string `json:"exclude_user_ids"`
+	// NegativeQuery - text the results should be far from, e.g. "frontend"
+	NegativeQuery string `json:"negative_query"`
+	// Diversity - zero value keeps the relevance order
+	Diversity Diversity `json:"diversity"`
+	// Passages - rank users by their description passages instead of the whole description
+	Passages PassageAggregation `json:"passages"`
+}
+
+// Diversity - MMR re-ordering of candidates with optional caps per team and location
+type Diversity struct {
+	// Lambda in (0, 1], 1 - relevance only, lower - more diverse. 0 disables MMR
+	Lambda         float64 `json:"lambda"`
+	MaxPerTeam     int     `json:"max_per_team"`
+	MaxPerLocation int     `json:"max_per_location"`
+}
+
+func (d Diversity) Enabled() bool {
+	return d.Lambda > 0 || d.MaxPerTeam > 0 || d.MaxPerLocation > 0
 }
 
 type VectorSearchResult struct {
-	Score  float32
-	UserID string
+	Score    float32
+	UserID   string
+	Team     string
+	Level    string
+	Location string
+	// Vector - dense description vector, only returned for MMR
+	Vector []float32
+	// Passages - best matching passages of the description, best first
+	Passages []PassageMatch
 }
 
 type IQdrantRepository interface {
@@ -32,20 +102,69 @@ type IQdrantRepository interface {
 	IndexUserWithVector(ctx context.Context, user *model.User, vector []float32) error
 	UpdateUserWithVector(ctx context.Context, user *model.User, vector []float32) error
 	DeleteUser(ctx context.Context, id string) error
-	SearchUserByVector(ctx context.Context, vector []float32, filter SearchFilters) ([]VectorSearchResult, error)
+	SearchUserByVector(ctx context.Context, vector []float32, negative []float32, filter SearchFilters) ([]VectorSearchResult, error)
+	SearchUserLexical(ctx context.Context, filter SearchFilters) ([]VectorSearchResult, error)
+	SearchUserHybrid(ctx context.Context, vector []float32, negative []float32, filter SearchFilters) ([]VectorSearchResult, error)
+	RecommendUsers(ctx context.Context, positive []string, negative []string, filter SearchFilters) ([]VectorSearchResult, error)
+	CountFacets(ctx context.Context, vector []float32, filter SearchFilters, threshold float32) (FacetCounts, error)
+	ScrollUsersWithoutOrganization(ctx context.Context, limit uint32) ([]string, error)
+	ScrollIndexedUsers(ctx context.Context, organizationID string) ([]IndexedUser, error)
+	RekeyPoints(ctx context.Context) (int, error)
+	SetUserOrganization(ctx context.Context, userID string, organizationID string) error
+	IndexGuidance(ctx context.Context, organizationID string, guidance []GuidanceVector) error
+	SearchGuidance(ctx context.Context, organizationID string, vector []float32) (GuidanceScores, error)
+	IndexUserPassages(ctx context.Context, user *model.User, passages []Passage) error
+	SearchPassages(ctx context.Context, vector []float32, filter SearchFilters, userIDs []string) ([]PassageHit, error)
+	BeginVersion(ctx context.Context) (CollectionVersion, error)
+	CopyPoints(ctx context.Context, version CollectionVersion, excludeOrganizationID string) (int, error)
+	CopyUsers(ctx context.Context, version CollectionVersion, userIDs []string) (int, error)
+	CommitVersion(ctx context.Context, version CollectionVersion) error
+	AbortVersion(ctx context.Context, version CollectionVersion) error
+	ActivateVersion(ctx context.Context, version string) error
+	ListVersions(ctx context.Context) ([]CollectionVersion, error)
+	CheckEmbeddingModel(ctx context.Context) error
+	ConfirmLegacyModel(ctx context.Context) (int, error)
+	EmbeddingStats(ctx context.Context, organizationID string) (EmbeddingStats, error)
+	InVersion(version CollectionVersion) IQdrantRepository
 }
 
 type repository struct {
-	client         *clients.QdrantClient
-	collectionName string
-	vectorSize     uint64
+	client *clients.QdrantClient
+	// collectionName and passagesCollection are the live aliases, or collections of a version when pinned
+	collectionName     string
+	passagesCollection string
+	vectorSize         uint64
+	modelID            string
+	modelVersion       string
+	keepVersions       int
+	versions           *versionState
+	pinned             bool
+}
+
+// EmbeddingModel - model that builds the vectors stored in the collections
+type EmbeddingModel interface {
+	ModelID() string
+	// Dimensions - 0 when the model could not tell
+	Dimensions() int
 }
 
-func New(cfg *config.Config, client *clients.QdrantClient) IQdrantRepository {
+// New refuses an embedding model whose vectors do not fit the configured collection size
+func New(cfg *config.Config, client *clients.QdrantClient, embedding EmbeddingModel) (IQdrantRepository, error) {
+	if dims := embedding.Dimensions(); dims == 0 {
+		fmt.Printf("Warning: dimensions of embedding model %s are unknown, EMBEDDER_DIMENSIONS=%d is not verified\n", embedding.ModelID(), cfg.Embedder.Dimensions)
+	} else if dims != cfg.Embedder.Dimensions {
+		return nil, fmt.Errorf("embedding model %s returns %d dimensions, EMBEDDER_DIMENSIONS is %d", embedding.ModelID(), dims, cfg.Embedder.Dimensions)
+	}
+
 	repo := &repository{
-		client:         client,
-		collectionName: UsersCollection,
-		vectorSize:     uint64(cfg.Embedder.Dimensions),
+		client:             client,
+		collectionName:     UsersAlias,
+		passagesCollection: PassagesAlias,
+		vectorSize:         uint64(cfg.Embedder.Dimensions),
+		modelID:            embedding.ModelID(),
+		modelVersion:       cfg.Embedder.ModelVersion,
+		keepVersions:       max(cfg.Qdrant.KeepVersions, 1),
+		versions:           &versionState{},
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -55,58 +174,106 @@ func New(cfg *config.Config, client *clients.QdrantClient) IQdrantRepository {
 		fmt.Printf("Warning: failed to initialize collection: %v\n", err)
 	}
 
-	return repo
+	return repo, nil
 }
 
+// InitializeCollection makes sure the live aliases exist and the live collections are up to date
 func (r *repository) InitializeCollection(ctx context.Context) error {
-	collections, err := r.client.Collections.List(ctx, &qdrant.ListCollectionsRequest{})
+	live, err := r.ensureAliases(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list collections: %w", err)
+		return err
 	}
 
-	exists := false
-	for _, c := range collections.Collections {
-		if c.Name == r.collectionName {
-			exists = true
-			break
-		}
+	if err := r.ensureLexicalVector(ctx, live.Users); err != nil {
+		return err
 	}
 
-	if !exists {
-		_, err = r.client.Collections.Create(ctx, &qdrant.CreateCollection{
-			CollectionName: r.collectionName,
-			VectorsConfig: &qdrant.VectorsConfig{
-				Config: &qdrant.VectorsConfig_Params{
-					Params: &qdrant.VectorParams{
-						Size:     r.vectorSize,
-						Distance: qdrant.Distance_Cosine,
-					},
+	// Indexes are created for existing collections too: older collections have no organization_id index
+	if err := r.createPayloadIndexes(ctx, live.Users); err != nil {
+		return fmt.Errorf("failed to create payload indexes: %w", err)
+	}
+	if err := r.createPassageIndexes(ctx, live.Passages); err != nil {
+		return err
+	}
+
+	return r.initializeGuidanceCollection(ctx)
+}
+
+func (r *repository) createUsersCollection(ctx context.Context, name string) error {
+	exists, err := r.client.Collections.CollectionExists(ctx, &qdrant.CollectionExistsRequest{CollectionName: name})
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", name, err)
+	}
+	if exists.GetResult().GetExists() {
+		return nil
+	}
+
+	_, err = r.client.Collections.Create(ctx, &qdrant.CreateCollection{
+		CollectionName: name,
+		VectorsConfig: &qdrant.VectorsConfig{
+			Config: &qdrant.VectorsConfig_Params{
+				Params: &qdrant.VectorParams{
+					Size:     r.vectorSize,
+					Distance: qdrant.Distance_Cosine,
 				},
 			},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create collection: %w", err)
-		}
+		},
+		SparseVectorsConfig: lexicalVectorsConfig(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", name, err)
+	}
+
+	return r.createPayloadIndexes(ctx, name)
+}
 
-		if err := r.createPayloadIndexes(ctx); err != nil {
-			return fmt.Errorf("failed to create payload indexes: %w", err)
+func lexicalVectorsConfig() *qdrant.SparseVectorConfig {
+	return qdrant.NewSparseVectorsConfig(map[string]*qdrant.SparseVectorParams{
+		LexicalVector: {Modifier: qdrant.Modifier_Idf.Enum()},
+	})
+}
+
+// ensureLexicalVector adds the sparse vector to collections created before hybrid search
+func (r *repository) ensureLexicalVector(ctx context.Context, name string) error {
+	info, err := r.client.Collections.Get(ctx, &qdrant.GetCollectionInfoRequest{CollectionName: name})
+	if err != nil {
+		return fmt.Errorf("failed to get collection info: %w", err)
+	}
+
+	if sparse := info.GetResult().GetConfig().GetParams().GetSparseVectorsConfig(); sparse != nil {
+		if _, ok := sparse.Map[LexicalVector]; ok {
+			return nil
 		}
 	}
 
+	_, err = r.client.Collections.Update(ctx, &qdrant.UpdateCollection{
+		CollectionName:      name,
+		SparseVectorsConfig: lexicalVectorsConfig(),
+	})
+	if err != nil {
+		return fmt.Errorf("collection %s has no %s vector, recreate it to enable lexical search: %w", name, LexicalVector, err)
+	}
+
 	return nil
 }
 
-func (r *repository) createPayloadIndexes(ctx context.Context) error {
+func (r *repository) createPayloadIndexes(ctx context.Context, name string) error {
 	indexes := []struct {
 		fieldName string
 		fieldType qdrant.FieldType
 	}{
 		{"user_id", qdrant.FieldType_FieldTypeKeyword},
+		{"organization_id", qdrant.FieldType_FieldTypeKeyword},
+		{"team", qdrant.FieldType_FieldTypeKeyword},
+		{"level", qdrant.FieldType_FieldTypeKeyword},
+		{"location", qdrant.FieldType_FieldTypeKeyword},
+		{EmbeddingModelField, qdrant.FieldType_FieldTypeKeyword},
+		{EmbeddingVersionField, qdrant.FieldType_FieldTypeKeyword},
 	}
 
 	for _, idx := range indexes {
 		_, err := r.client.Points.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
-			CollectionName: r.collectionName,
+			CollectionName: name,
 			FieldName:      idx.fieldName,
 			FieldType:      &idx.fieldType,
 		})
@@ -129,26 +296,32 @@ func (r *repository) IndexUserWithVector(ctx context.Context, user *model.User,
 		return fmt.Errorf("failed to create payload: %w", err)
 	}
 
+	lexical := lib.SparseDocumentVector(user.Semantic.Description)
+	vectors := map[string]*qdrant.Vector{
+		DenseVector: qdrant.NewVectorDense(vector),
+	}
+	// Qdrant rejects empty sparse vectors
+	if len(lexical.Indices) > 0 {
+		vectors[LexicalVector] = qdrant.NewVectorSparse(lexical.Indices, lexical.Values)
+	}
+
 	point := &qdrant.PointStruct{
-		Id: &qdrant.PointId{
-			PointIdOptions: &qdrant.PointId_Num{
-				Num: pointID,
-			},
-		},
-		Vectors: &qdrant.Vectors{
-			VectorsOptions: &qdrant.Vectors_Vector{
-				Vector: &qdrant.Vector{Data: vector},
-			},
-		},
+		Id:      pointID,
+		Vectors: qdrant.NewVectorsMap(vectors),
 		Payload: payload,
 	}
 
-	_, err = r.client.Points.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: r.collectionName,
-		Points:         []*qdrant.PointStruct{point},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to index user: %w", err)
+	for _, collection := range r.userCollections() {
+		if err := r.checkPointOwner(ctx, collection, pointID, user.ID.Hex()); err != nil {
+			return err
+		}
+		_, err = r.client.Points.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: collection,
+			Points:         []*qdrant.PointStruct{point},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to index user: %w", err)
+		}
 	}
 
 	return nil
@@ -164,43 +337,49 @@ func (r *repository) DeleteUser(ctx context.Context, id string) error {
 		return fmt.Errorf("failed to convert user ID: %w", err)
 	}
 
-	_, err = r.client.Points.Delete(ctx, &qdrant.DeletePoints{
-		CollectionName: r.collectionName,
-		Points: &qdrant.PointsSelector{
-			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
-				Points: &qdrant.PointsIdsList{
-					Ids: []*qdrant.PointId{
-						{PointIdOptions: &qdrant.PointId_Num{Num: pointID}},
+	for _, collection := range r.userCollections() {
+		_, err = r.client.Points.Delete(ctx, &qdrant.DeletePoints{
+			CollectionName: collection,
+			Points: &qdrant.PointsSelector{
+				PointsSelectorOneOf: &qdrant.PointsSelector_Points{
+					Points: &qdrant.PointsIdsList{
+						Ids: []*qdrant.PointId{pointID},
 					},
 				},
 			},
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
 	}
 
-	return nil
+	return r.deleteUserPassages(ctx, id)
 }
 
-func (r *repository) SearchUserByVector(ctx context.Context, vector []float32, filters SearchFilters) ([]VectorSearchResult, error) {
-	must := make([]*qdrant.Condition, 0)
-
-	if len(filters.Teams) > 0 {
-		must = append(must, qdrant.NewMatchKeywords("team", filters.Teams...))
+// SearchUserByVector - with a negative vector the query turns into a recommend query that avoids it
+func (r *repository) SearchUserByVector(ctx context.Context, vector []float32, negative []float32, filters SearchFilters) ([]VectorSearchResult, error) {
+	if err := r.denseSearchable(); err != nil {
+		return nil, err
 	}
-
-	if len(filters.Levels) > 0 {
-		must = append(must, qdrant.NewMatchKeywords("level", filters.Levels...))
-	}
-
-	if len(filters.Locations) > 0 {
-		must = append(must, qdrant.NewMatchKeywords("location", filters.Locations...))
+	filter, err := buildSearchFilter(filters)
+	if err != nil {
+		return nil, err
 	}
 
-	var filter *qdrant.Filter
-	if len(must) > 0 {
-		filter = &qdrant.Filter{Must: must}
+	if len(negative) > 0 {
+		resp, err := r.client.Points.Query(ctx, &qdrant.QueryPoints{
+			CollectionName: r.collectionName,
+			Query:          denseQuery(vector, negative),
+			Using:          qdrant.PtrOf(DenseVector),
+			Filter:         filter,
+			Limit:          qdrant.PtrOf(filters.Limit),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    withDenseVector(filters),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search users: %w", err)
+		}
+		return r.scoredPointsToResults(resp.Result), nil
 	}
 
 	resp, err := r.client.Points.Search(ctx, &qdrant.SearchPoints{
@@ -211,42 +390,316 @@ func (r *repository) SearchUserByVector(ctx context.Context, vector []float32, f
 		WithPayload: &qdrant.WithPayloadSelector{
 			SelectorOptions: &qdrant.WithPayloadSelector_Enable{Enable: true},
 		},
+		WithVectors: withDenseVector(filters),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
 
-	results := make([]VectorSearchResult, 0, len(resp.Result))
-	for _, point := range resp.Result {
+	return r.scoredPointsToResults(resp.Result), nil
+}
+
+// SearchUserLexical ranks users by the sparse BM25 vector only, exact terms like "SOC2" or "Kafka"
+func (r *repository) SearchUserLexical(ctx context.Context, filters SearchFilters) ([]VectorSearchResult, error) {
+	filter, err := buildSearchFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	lexical := lib.SparseQueryVector(filters.Query)
+	if len(lexical.Indices) == 0 {
+		return []VectorSearchResult{}, nil
+	}
+
+	resp, err := r.client.Points.Query(ctx, &qdrant.QueryPoints{
+		CollectionName: r.collectionName,
+		Query:          qdrant.NewQuerySparse(lexical.Indices, lexical.Values),
+		Using:          qdrant.PtrOf(LexicalVector),
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(filters.Limit),
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    withDenseVector(filters),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users lexically: %w", err)
+	}
+
+	return r.scoredPointsToResults(resp.Result), nil
+}
+
+// SearchUserHybrid fuses dense and sparse rankings with reciprocal rank fusion
+func (r *repository) SearchUserHybrid(ctx context.Context, vector []float32, negative []float32, filters SearchFilters) ([]VectorSearchResult, error) {
+	if err := r.denseSearchable(); err != nil {
+		return nil, err
+	}
+	filter, err := buildSearchFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every ranking is over-fetched, otherwise fusion can only reorder the same few points
+	prefetchLimit := max(filters.Limit*4, 20)
+	prefetch := []*qdrant.PrefetchQuery{
+		{
+			Query:  denseQuery(vector, negative),
+			Using:  qdrant.PtrOf(DenseVector),
+			Filter: filter,
+			Limit:  qdrant.PtrOf(prefetchLimit),
+		},
+	}
+
+	lexical := lib.SparseQueryVector(filters.Query)
+	if len(lexical.Indices) > 0 {
+		prefetch = append(prefetch, &qdrant.PrefetchQuery{
+			Query:  qdrant.NewQuerySparse(lexical.Indices, lexical.Values),
+			Using:  qdrant.PtrOf(LexicalVector),
+			Filter: filter,
+			Limit:  qdrant.PtrOf(prefetchLimit),
+		})
+	}
+
+	resp, err := r.client.Points.Query(ctx, &qdrant.QueryPoints{
+		CollectionName: r.collectionName,
+		Prefetch:       prefetch,
+		Query:          qdrant.NewQueryFusion(qdrant.Fusion_RRF),
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(filters.Limit),
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    withDenseVector(filters),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users hybrid: %w", err)
+	}
+
+	return r.scoredPointsToResults(resp.Result), nil
+}
+
+// withDenseVector - vectors are only transferred when MMR needs them
+func withDenseVector(filters SearchFilters) *qdrant.WithVectorsSelector {
+	if filters.Diversity.Lambda <= 0 {
+		return nil
+	}
+	return qdrant.NewWithVectorsInclude(DenseVector)
+}
+
+func denseVectorOf(vectors *qdrant.VectorsOutput) []float32 {
+	output := vectors.GetVector()
+	if output == nil {
+		output = vectors.GetVectors().GetVectors()[DenseVector]
+	}
+	if dense := output.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	return output.GetData()
+}
+
+// denseQuery - plain nearest search, or best-score recommendation when there is something to avoid
+func denseQuery(vector []float32, negative []float32) *qdrant.Query {
+	if len(negative) == 0 {
+		return qdrant.NewQueryDense(vector)
+	}
+	return qdrant.NewQueryRecommend(&qdrant.RecommendInput{
+		Positive: []*qdrant.VectorInput{qdrant.NewVectorInput(vector...)},
+		Negative: []*qdrant.VectorInput{qdrant.NewVectorInput(negative...)},
+		Strategy: qdrant.RecommendStrategy_BestScore.Enum(),
+	})
+}
+
+// RecommendUsers finds users close to the stored points of positive users and away from negative ones.
+// Example points themselves are never returned
+func (r *repository) RecommendUsers(ctx context.Context, positive []string, negative []string, filters SearchFilters) ([]VectorSearchResult, error) {
+	if err := r.denseSearchable(); err != nil {
+		return nil, err
+	}
+	filter, err := buildSearchFilter(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	positiveIDs, err := r.userIDsToPointIDs(positive)
+	if err != nil {
+		return nil, err
+	}
+	negativeIDs, err := r.userIDsToPointIDs(negative)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &qdrant.RecommendPoints{
+		CollectionName: r.collectionName,
+		Positive:       positiveIDs,
+		Negative:       negativeIDs,
+		Filter:         filter,
+		Limit:          filters.Limit,
+		WithPayload:    qdrant.NewWithPayload(true),
+	}
+	// Average vector ignores how far a candidate is from each single negative example
+	if len(negativeIDs) > 0 {
+		request.Strategy = qdrant.RecommendStrategy_BestScore.Enum()
+	}
+
+	resp, err := r.client.Points.Recommend(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recommend users: %w", err)
+	}
+
+	return r.scoredPointsToResults(resp.Result), nil
+}
+
+func (r *repository) scoredPointsToResults(points []*qdrant.ScoredPoint) []VectorSearchResult {
+	results := make([]VectorSearchResult, 0, len(points))
+	for _, point := range points {
 		userID, err := r.payloadToUser(point.Payload)
 		if err != nil {
 			fmt.Printf("Warning: failed to convert payload to userID: %v\n", err)
 			continue
 		}
 		results = append(results, VectorSearchResult{
-			Score:  point.Score,
-			UserID: userID,
+			Score:    point.Score,
+			UserID:   userID,
+			Team:     point.Payload["team"].GetStringValue(),
+			Level:    point.Payload["level"].GetStringValue(),
+			Location: point.Payload["location"].GetStringValue(),
+			Vector:   denseVectorOf(point.Vectors),
 		})
 	}
+	return results
+}
 
-	return results, nil
+// ScrollUsersWithoutOrganization returns user ids of points indexed before organization_id was added to the payload.
+// Points without a valid user id are deleted on the way
+func (r *repository) ScrollUsersWithoutOrganization(ctx context.Context, limit uint32) ([]string, error) {
+	for {
+		resp, err := r.client.Points.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: r.collectionName,
+			Filter: &qdrant.Filter{
+				Must: []*qdrant.Condition{qdrant.NewIsEmpty("organization_id")},
+			},
+			Limit:       &limit,
+			WithPayload: qdrant.NewWithPayloadInclude("user_id"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll users: %w", err)
+		}
+
+		userIDs := make([]string, 0, len(resp.Result))
+		orphans := make([]*qdrant.PointId, 0)
+		for _, point := range resp.Result {
+			userID, err := r.payloadToUser(point.Payload)
+			if err == nil {
+				_, err = r.userIDToPointID(userID)
+			}
+			if err != nil {
+				orphans = append(orphans, point.Id)
+				continue
+			}
+			userIDs = append(userIDs, userID)
+		}
+
+		// Points without a user can't be attributed to any organization, they would stay
+		// invisible to tenant-scoped search and end the backfill before the points after them
+		if len(orphans) > 0 {
+			_, err := r.client.Points.Delete(ctx, &qdrant.DeletePoints{
+				CollectionName: r.collectionName,
+				Wait:           qdrant.PtrOf(true),
+				Points:         qdrant.NewPointsSelector(orphans...),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete points without user: %w", err)
+			}
+		}
+
+		if len(userIDs) > 0 || len(orphans) == 0 {
+			return userIDs, nil
+		}
+	}
+}
+
+func (r *repository) SetUserOrganization(ctx context.Context, userID string, organizationID string) error {
+	pointID, err := r.userIDToPointID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to convert user ID: %w", err)
+	}
+
+	_, err = r.client.Points.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: r.collectionName,
+		Payload: map[string]*qdrant.Value{
+			"organization_id": qdrant.NewValueString(organizationID),
+		},
+		PointsSelector: qdrant.NewPointsSelector(pointID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set user organization: %w", err)
+	}
+
+	return nil
 }
 
-func (r *repository) userIDToPointID(userID string) (uint64, error) {
-	var hash uint64
-	for _, char := range userID {
-		hash = hash*31 + uint64(char)
+// buildSearchFilter - organization condition is mandatory, so a search can never leave the tenant
+func buildSearchFilter(filters SearchFilters) (*qdrant.Filter, error) {
+	if filters.OrganizationID == "" {
+		return nil, ErrMissingOrganization
+	}
+
+	must := []*qdrant.Condition{
+		qdrant.NewMatchKeyword("organization_id", filters.OrganizationID),
+	}
+
+	if len(filters.Teams) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("team", filters.Teams...))
+	}
+
+	if len(filters.Levels) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("level", filters.Levels...))
+	}
+
+	if len(filters.Locations) > 0 {
+		must = append(must, qdrant.NewMatchKeywords("location", filters.Locations...))
+	}
+
+	var mustNot []*qdrant.Condition
+
+	if len(filters.ExcludeTeams) > 0 {
+		mustNot = append(mustNot, qdrant.NewMatchKeywords("team", filters.ExcludeTeams...))
+	}
+
+	if len(filters.ExcludeLevels) > 0 {
+		mustNot = append(mustNot, qdrant.NewMatchKeywords("level", filters.ExcludeLevels...))
+	}
+
+	if len(filters.ExcludeLocations) > 0 {
+		mustNot = append(mustNot, qdrant.NewMatchKeywords("location", filters.ExcludeLocations...))
+	}
+
+	if len(filters.ExcludeUserIDs) > 0 {
+		mustNot = append(mustNot, qdrant.NewMatchKeywords("user_id", filters.ExcludeUserIDs...))
+	}
+
+	return &qdrant.Filter{Must: must, MustNot: mustNot}, nil
+}
+
+func (r *repository) userIDsToPointIDs(userIDs []string) ([]*qdrant.PointId, error) {
+	ids := make([]*qdrant.PointId, 0, len(userIDs))
+	for _, userID := range userIDs {
+		pointID, err := r.userIDToPointID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert user ID: %w", err)
+		}
+		ids = append(ids, pointID)
 	}
-	return hash, nil
+	return ids, nil
 }
 
 func (r *repository) userToPayload(user *model.User) (map[string]*qdrant.Value, error) {
 	payload := map[string]*qdrant.Value{
-		"user_id":  {Kind: &qdrant.Value_StringValue{StringValue: user.ID.Hex()}},
-		"team":     {Kind: &qdrant.Value_StringValue{StringValue: user.Semantic.Team.Hex()}},
-		"level":    {Kind: &qdrant.Value_StringValue{StringValue: user.Semantic.Level.Hex()}},
-		"location": {Kind: &qdrant.Value_StringValue{StringValue: user.Semantic.Location.Hex()}},
+		"user_id":         {Kind: &qdrant.Value_StringValue{StringValue: user.ID.Hex()}},
+		"organization_id": {Kind: &qdrant.Value_StringValue{StringValue: user.OrganizationID.Hex()}},
+		"team":            {Kind: &qdrant.Value_StringValue{StringValue: user.Semantic.Team.Hex()}},
+		"level":           {Kind: &qdrant.Value_StringValue{StringValue: user.Semantic.Level.Hex()}},
+		"location":        {Kind: &qdrant.Value_StringValue{StringValue: user.Semantic.Location.Hex()}},
+		ContentHashField:  {Kind: &qdrant.Value_StringValue{StringValue: UserContentHash(user)}},
 	}
+	r.stampModel(payload)
 	return payload, nil
 }
 
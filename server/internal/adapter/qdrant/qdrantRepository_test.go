@@ -0,0 +1,105 @@
+package qdrant
+
+import (
+	"context"
+	"fmt"
+	"github.com/qdrant/go-client/qdrant"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSearchFilterRequiresOrganization(t *testing.T) {
+	_, err := buildSearchFilter(SearchFilters{Query: "kafka", Teams: []string{"team"}})
+	assert.ErrorIs(t, err, ErrMissingOrganization)
+}
+
+func TestSearchFilterScopesOrganization(t *testing.T) {
+	filter, err := buildSearchFilter(SearchFilters{
+		OrganizationID: "org",
+		Teams:          []string{"team"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, filter.Must, 2)
+	assert.Equal(t, "organization_id", filter.Must[0].GetField().GetKey())
+	assert.Equal(t, "org", filter.Must[0].GetField().GetMatch().GetKeyword())
+}
+
+func TestSearchFilterExcludes(t *testing.T) {
+	filter, err := buildSearchFilter(SearchFilters{
+		OrganizationID: "org",
+		ExcludeTeams:   []string{"sales"},
+		ExcludeUserIDs: []string{"a", "b"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, filter.Must, 1)
+	assert.Len(t, filter.MustNot, 2)
+	assert.Equal(t, "team", filter.MustNot[0].GetField().GetKey())
+	assert.Equal(t, "user_id", filter.MustNot[1].GetField().GetKey())
+	assert.Equal(t, []string{"a", "b"}, filter.MustNot[1].GetField().GetMatch().GetKeywords().GetStrings())
+}
+
+func TestParseSearchMode(t *testing.T) {
+	mode, err := ParseSearchMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, SearchModes.Semantic, mode)
+
+	mode, err = ParseSearchMode("hybrid")
+	assert.NoError(t, err)
+	assert.Equal(t, SearchModes.Hybrid, mode)
+
+	_, err = ParseSearchMode("fuzzy")
+	assert.Error(t, err)
+}
+
+func TestCollectionVersionNames(t *testing.T) {
+	base := newCollectionVersion(BaseVersion)
+	assert.Equal(t, UsersCollection, base.Users)
+	assert.Equal(t, PassagesCollection, base.Passages)
+
+	next := newCollectionVersion("v20250101120000")
+	version, ok := versionOfUsersCollection(next.Users)
+	assert.True(t, ok)
+	assert.Equal(t, next.Version, version)
+
+	version, ok = versionOfUsersCollection(base.Users)
+	assert.True(t, ok)
+	assert.Equal(t, BaseVersion, version)
+
+	_, ok = versionOfUsersCollection(GuidanceCollection)
+	assert.False(t, ok)
+	assert.Less(t, versionOrder(BaseVersion), versionOrder(next.Version))
+}
+
+func TestObjectPointIDKeepsWholeObjectID(t *testing.T) {
+	a, err := objectPointID("65f1a2b3c4d5e6f708090a0b", 0)
+	assert.NoError(t, err)
+	again, _ := objectPointID("65f1a2b3c4d5e6f708090a0b", 0)
+	assert.Equal(t, a.GetUuid(), again.GetUuid())
+	assert.Equal(t, "65f1a2b3-c4d5-8000-8000-e6f708090a0b", a.GetUuid())
+
+	// Differs only in the counter bytes, which the old string hash could map to the same number
+	b, _ := objectPointID("65f1a2b3c4d5e6f708090a0c", 0)
+	assert.NotEqual(t, a.GetUuid(), b.GetUuid())
+
+	passage, err := passagePointID("65f1a2b3c4d5e6f708090a0b", 258)
+	assert.NoError(t, err)
+	assert.Equal(t, "65f1a2b3-c4d5-8001-8002-e6f708090a0b", passage.GetUuid())
+
+	_, err = objectPointID("not-an-object-id", 0)
+	assert.Error(t, err)
+}
+
+func TestModelMismatchRefusesDenseSearch(t *testing.T) {
+	repo := &repository{modelID: "e5-large", modelVersion: "2", versions: &versionState{}}
+	payload := map[string]*qdrant.Value{}
+	repo.stampModel(payload)
+	assert.Equal(t, "e5-large", payload[EmbeddingModelField].GetStringValue())
+	assert.Equal(t, "2", payload[EmbeddingVersionField].GetStringValue())
+
+	repo.setModelMismatch(fmt.Errorf("%w: 3 points of users were not built with e5-large@2", ErrModelMismatch))
+	filters := SearchFilters{OrganizationID: "org", Limit: 5}
+	_, err := repo.SearchUserByVector(context.Background(), []float32{1}, nil, filters)
+	assert.ErrorIs(t, err, ErrModelMismatch)
+	_, err = repo.InVersion(CollectionVersion{Users: "users_v1", Passages: "user_passages_v1"}).SearchPassages(context.Background(), []float32{1}, filters, nil)
+	assert.ErrorIs(t, err, ErrModelMismatch, "pinned copies share the state")
+}
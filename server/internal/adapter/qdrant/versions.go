@@ -0,0 +1,506 @@
+package qdrant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const (
+	// UsersAlias - search and writes go through the alias, reindexing switches it to a new version
+	UsersAlias = "users_live"
+	// PassagesAlias - passages are embedded with the same model, so they are versioned together with users
+	PassagesAlias = "user_passages_live"
+	// BaseVersion - collections created before versioning, "users" and "user_passages"
+	BaseVersion = "base"
+	// completeAliasPrefix - an alias per committed version, collections without it were left by a crashed build
+	completeAliasPrefix = "complete_"
+	// copyBatchSize - points moved per scroll page when a version is seeded from the active one
+	copyBatchSize = 256
+)
+
+// ErrBuildInProgress - only one version is built at a time, otherwise the second switch would drop the first one
+var ErrBuildInProgress = errors.New("another collection version is being built")
+
+// CollectionVersion - users and passages collections built by one reindex
+type CollectionVersion struct {
+	Version  string `json:"version"`
+	Users    string `json:"users"`
+	Passages string `json:"passages"`
+	Active   bool   `json:"active"`
+	// complete - the version was committed, see markComplete
+	complete bool
+}
+
+// versionState is shared by the repository and its pinned copies
+type versionState struct {
+	build    sync.Mutex
+	mu       sync.RWMutex
+	building *CollectionVersion
+	model    modelState
+}
+
+func (v *versionState) current() *CollectionVersion {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.building
+}
+
+func (v *versionState) set(version *CollectionVersion) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.building = version
+}
+
+func newCollectionVersion(version string) CollectionVersion {
+	if version == BaseVersion {
+		return CollectionVersion{Version: version, Users: UsersCollection, Passages: PassagesCollection}
+	}
+	return CollectionVersion{
+		Version:  version,
+		Users:    UsersCollection + "_" + version,
+		Passages: PassagesCollection + "_" + version,
+	}
+}
+
+// versionOfUsersCollection is the inverse of newCollectionVersion, false for unrelated collections
+func versionOfUsersCollection(name string) (string, bool) {
+	if name == UsersCollection {
+		return BaseVersion, true
+	}
+	if version, ok := strings.CutPrefix(name, UsersCollection+"_v"); ok && version != "" {
+		return "v" + version, true
+	}
+	return "", false
+}
+
+// userCollections - writes go to the live alias and, while a version is built, to the new version as well
+func (r *repository) userCollections() []string {
+	if r.pinned {
+		return []string{r.collectionName}
+	}
+	if building := r.versions.current(); building != nil {
+		return []string{r.collectionName, building.Users}
+	}
+	return []string{r.collectionName}
+}
+
+func (r *repository) passageCollections() []string {
+	if r.pinned {
+		return []string{r.passagesCollection}
+	}
+	if building := r.versions.current(); building != nil {
+		return []string{r.passagesCollection, building.Passages}
+	}
+	return []string{r.passagesCollection}
+}
+
+func completeAlias(version CollectionVersion) string {
+	return completeAliasPrefix + version.Users
+}
+
+// markComplete records that every user of the version was built or copied, only complete versions can be activated
+func (r *repository) markComplete(ctx context.Context, version CollectionVersion) error {
+	if r.hasAlias(ctx, completeAlias(version)) {
+		return nil
+	}
+	_, err := r.client.Collections.UpdateAliases(ctx, &qdrant.ChangeAliases{
+		Actions: []*qdrant.AliasOperations{qdrant.NewAliasCreate(completeAlias(version), version.Users)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark version %s complete: %w", version.Version, err)
+	}
+	return nil
+}
+
+// ensureAliases points the aliases to the base collections on the first start, returns the live version
+func (r *repository) ensureAliases(ctx context.Context) (CollectionVersion, error) {
+	aliases, err := r.client.Collections.ListAliases(ctx, &qdrant.ListAliasesRequest{})
+	if err != nil {
+		return CollectionVersion{}, fmt.Errorf("failed to list aliases: %w", err)
+	}
+
+	for _, alias := range aliases.GetAliases() {
+		if alias.GetAliasName() != UsersAlias {
+			continue
+		}
+		version, ok := versionOfUsersCollection(alias.GetCollectionName())
+		if !ok {
+			return CollectionVersion{}, fmt.Errorf("alias %s points to unknown collection %s", UsersAlias, alias.GetCollectionName())
+		}
+		live := newCollectionVersion(version)
+		live.Active = true
+		// Passages may be missing for versions created before passages existed
+		if err := r.createPassagesCollection(ctx, live.Passages); err != nil {
+			return CollectionVersion{}, err
+		}
+		// The live version was committed, also when that happened before versions were marked
+		if err := r.markComplete(ctx, live); err != nil {
+			return CollectionVersion{}, err
+		}
+		return live, r.switchAliases(ctx, live)
+	}
+
+	live := newCollectionVersion(BaseVersion)
+	live.Active = true
+	if err := r.createUsersCollection(ctx, live.Users); err != nil {
+		return CollectionVersion{}, err
+	}
+	if err := r.createPassagesCollection(ctx, live.Passages); err != nil {
+		return CollectionVersion{}, err
+	}
+	_, err = r.client.Collections.UpdateAliases(ctx, &qdrant.ChangeAliases{
+		Actions: []*qdrant.AliasOperations{
+			qdrant.NewAliasCreate(UsersAlias, live.Users),
+			qdrant.NewAliasCreate(PassagesAlias, live.Passages),
+			qdrant.NewAliasCreate(completeAlias(live), live.Users),
+		},
+	})
+	if err != nil {
+		return CollectionVersion{}, fmt.Errorf("failed to create aliases: %w", err)
+	}
+	return live, nil
+}
+
+// switchAliases moves both aliases in one request, Qdrant applies the actions atomically
+func (r *repository) switchAliases(ctx context.Context, version CollectionVersion) error {
+	actions := make([]*qdrant.AliasOperations, 0, 4)
+	for _, alias := range []string{UsersAlias, PassagesAlias} {
+		if r.hasAlias(ctx, alias) {
+			actions = append(actions, qdrant.NewAliasDelete(alias))
+		}
+	}
+	actions = append(actions,
+		qdrant.NewAliasCreate(UsersAlias, version.Users),
+		qdrant.NewAliasCreate(PassagesAlias, version.Passages),
+	)
+	_, err := r.client.Collections.UpdateAliases(ctx, &qdrant.ChangeAliases{Actions: actions})
+	if err != nil {
+		return fmt.Errorf("failed to switch aliases to %s: %w", version.Version, err)
+	}
+	return nil
+}
+
+func (r *repository) hasAlias(ctx context.Context, alias string) bool {
+	aliases, err := r.client.Collections.ListAliases(ctx, &qdrant.ListAliasesRequest{})
+	if err != nil {
+		return true
+	}
+	for _, a := range aliases.GetAliases() {
+		if a.GetAliasName() == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// BeginVersion creates empty collections of a new version. Until it is committed or aborted
+// every write to the live collections is also applied to the new version
+func (r *repository) BeginVersion(ctx context.Context) (CollectionVersion, error) {
+	if !r.versions.build.TryLock() {
+		return CollectionVersion{}, ErrBuildInProgress
+	}
+
+	version := newCollectionVersion("v" + time.Now().UTC().Format("20060102150405"))
+	if err := r.createUsersCollection(ctx, version.Users); err != nil {
+		r.versions.build.Unlock()
+		return CollectionVersion{}, err
+	}
+	if err := r.createPassagesCollection(ctx, version.Passages); err != nil {
+		r.versions.build.Unlock()
+		return CollectionVersion{}, err
+	}
+
+	r.versions.set(&version)
+	return version, nil
+}
+
+// CopyPoints seeds the version with live points of every organization except the excluded one,
+// vectors are copied as they are, whatever model built them. Returns the number of copied users
+func (r *repository) CopyPoints(ctx context.Context, version CollectionVersion, excludeOrganizationID string) (int, error) {
+	var filter *qdrant.Filter
+	if excludeOrganizationID != "" {
+		filter = &qdrant.Filter{MustNot: []*qdrant.Condition{qdrant.NewMatchKeyword("organization_id", excludeOrganizationID)}}
+	}
+
+	copied, err := r.copyCollection(ctx, r.collectionName, version.Users, filter)
+	if err != nil {
+		return copied, err
+	}
+	if _, err := r.copyCollection(ctx, r.passagesCollection, version.Passages, filter); err != nil {
+		return copied, err
+	}
+	return copied, nil
+}
+
+// CopyUsers copies the live points and passages of the users into the version, e.g. for users
+// a reindex failed to embed. Returns the number of copied users
+func (r *repository) CopyUsers(ctx context.Context, version CollectionVersion, userIDs []string) (int, error) {
+	copied := 0
+	for batch := range slices.Chunk(userIDs, copyBatchSize) {
+		filter := &qdrant.Filter{Must: []*qdrant.Condition{qdrant.NewMatchKeywords("user_id", batch...)}}
+		users, err := r.copyCollection(ctx, r.collectionName, version.Users, filter)
+		copied += users
+		if err != nil {
+			return copied, err
+		}
+		if _, err := r.copyCollection(ctx, r.passagesCollection, version.Passages, filter); err != nil {
+			return copied, err
+		}
+	}
+	return copied, nil
+}
+
+func (r *repository) copyCollection(ctx context.Context, from string, to string, filter *qdrant.Filter) (int, error) {
+	copied := 0
+	var offset *qdrant.PointId
+	for {
+		resp, err := r.client.Points.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: from,
+			Filter:         filter,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(copyBatchSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return copied, fmt.Errorf("failed to scroll %s: %w", from, err)
+		}
+
+		if len(resp.Result) > 0 {
+			points := make([]*qdrant.PointStruct, 0, len(resp.Result))
+			for _, point := range resp.Result {
+				points = append(points, &qdrant.PointStruct{
+					Id:      point.Id,
+					Vectors: vectorsOutputToInput(point.Vectors),
+					Payload: point.Payload,
+				})
+			}
+			_, err = r.client.Points.Upsert(ctx, &qdrant.UpsertPoints{
+				CollectionName: to,
+				Wait:           qdrant.PtrOf(true),
+				Points:         points,
+			})
+			if err != nil {
+				return copied, fmt.Errorf("failed to copy points to %s: %w", to, err)
+			}
+			copied += len(points)
+		}
+
+		if resp.NextPageOffset == nil {
+			return copied, nil
+		}
+		offset = resp.NextPageOffset
+	}
+}
+
+func vectorsOutputToInput(vectors *qdrant.VectorsOutput) *qdrant.Vectors {
+	if single := vectors.GetVector(); single != nil {
+		return qdrant.NewVectorsDense(denseVectorOf(vectors))
+	}
+
+	named := make(map[string]*qdrant.Vector, len(vectors.GetVectors().GetVectors()))
+	for name, output := range vectors.GetVectors().GetVectors() {
+		if sparse := output.GetSparse(); sparse != nil {
+			named[name] = qdrant.NewVectorSparse(sparse.GetIndices(), sparse.GetValues())
+		} else if indices := output.GetIndices(); indices != nil {
+			named[name] = qdrant.NewVectorSparse(indices.GetData(), output.GetData())
+		} else if dense := output.GetDense(); dense != nil {
+			named[name] = qdrant.NewVectorDense(dense.GetData())
+		} else {
+			named[name] = qdrant.NewVectorDense(output.GetData())
+		}
+	}
+	return qdrant.NewVectorsMap(named)
+}
+
+// CommitVersion marks the version complete, switches the aliases to it and drops versions beyond the retention.
+// Points copied from other organizations may come from another model, dense search stays disabled until they are reindexed
+func (r *repository) CommitVersion(ctx context.Context, version CollectionVersion) error {
+	defer r.versions.build.Unlock()
+	defer r.versions.set(nil)
+
+	mismatch := r.checkVersionModel(ctx, version)
+	if mismatch != nil && !errors.Is(mismatch, ErrModelMismatch) {
+		return mismatch
+	}
+	if err := r.markComplete(ctx, version); err != nil {
+		return err
+	}
+	if err := r.switchAliases(ctx, version); err != nil {
+		return err
+	}
+	r.setModelMismatch(mismatch)
+	return r.pruneVersions(ctx, version)
+}
+
+// AbortVersion drops the collections of a version that was not committed
+func (r *repository) AbortVersion(ctx context.Context, version CollectionVersion) error {
+	defer r.versions.build.Unlock()
+	r.versions.set(nil)
+	return r.deleteVersion(ctx, version)
+}
+
+// ActivateVersion switches the aliases to a complete version, e.g. to roll back a reindex.
+// Versions holding vectors of another embedding model or unstamped points are refused with ErrModelMismatch
+func (r *repository) ActivateVersion(ctx context.Context, version string) error {
+	if !r.versions.build.TryLock() {
+		return ErrBuildInProgress
+	}
+	defer r.versions.build.Unlock()
+
+	versions, err := r.ListVersions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			if err := r.createPassagesCollection(ctx, v.Passages); err != nil {
+				return err
+			}
+			// Versions built before UUID point ids would bring numeric ids back
+			if _, err := r.rekeyVersion(ctx, v); err != nil {
+				return err
+			}
+			if err := r.checkVersionModel(ctx, v); err != nil {
+				return err
+			}
+			if err := r.switchAliases(ctx, v); err != nil {
+				return err
+			}
+			r.setModelMismatch(nil)
+			return nil
+		}
+	}
+	return fmt.Errorf("collection version %s not found", version)
+}
+
+// ListVersions returns complete versions, oldest first
+func (r *repository) ListVersions(ctx context.Context) ([]CollectionVersion, error) {
+	versions, err := r.listVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	complete := make([]CollectionVersion, 0, len(versions))
+	for _, version := range versions {
+		if version.complete {
+			complete = append(complete, version)
+		}
+	}
+	return complete, nil
+}
+
+// listVersions returns every version but the one being built, oldest first
+func (r *repository) listVersions(ctx context.Context) ([]CollectionVersion, error) {
+	collections, err := r.client.Collections.List(ctx, &qdrant.ListCollectionsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	aliases, err := r.client.Collections.ListAliases(ctx, &qdrant.ListAliasesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list aliases: %w", err)
+	}
+	live := ""
+	complete := make(map[string]bool)
+	for _, alias := range aliases.GetAliases() {
+		if alias.GetAliasName() == UsersAlias {
+			live = alias.GetCollectionName()
+		}
+		if strings.HasPrefix(alias.GetAliasName(), completeAliasPrefix) {
+			complete[alias.GetCollectionName()] = true
+		}
+	}
+
+	building := r.versions.current()
+	versions := make([]CollectionVersion, 0)
+	for _, c := range collections.GetCollections() {
+		name, ok := versionOfUsersCollection(c.GetName())
+		if !ok || (building != nil && building.Version == name) {
+			continue
+		}
+		version := newCollectionVersion(name)
+		version.Active = version.Users == live
+		version.complete = complete[version.Users]
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versionOrder(versions[i].Version) < versionOrder(versions[j].Version)
+	})
+	return versions, nil
+}
+
+// versionOrder - base is older than any timestamped version
+func versionOrder(version string) string {
+	if version == BaseVersion {
+		return ""
+	}
+	return version
+}
+
+// pruneVersions keeps the live version and the newest inactive ones up to keepVersions in total.
+// Incomplete versions older than the committed one were left by a build that never finished
+func (r *repository) pruneVersions(ctx context.Context, committed CollectionVersion) error {
+	versions, err := r.listVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Active {
+			continue
+		}
+		if !versions[i].complete {
+			if versionOrder(versions[i].Version) < versionOrder(committed.Version) {
+				if err := r.deleteVersion(ctx, versions[i]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		kept++
+		if kept < r.keepVersions {
+			continue
+		}
+		if err := r.deleteVersion(ctx, versions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repository) deleteVersion(ctx context.Context, version CollectionVersion) error {
+	if r.hasAlias(ctx, completeAlias(version)) {
+		_, err := r.client.Collections.UpdateAliases(ctx, &qdrant.ChangeAliases{
+			Actions: []*qdrant.AliasOperations{qdrant.NewAliasDelete(completeAlias(version))},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unmark version %s: %w", version.Version, err)
+		}
+	}
+	for _, name := range []string{version.Users, version.Passages} {
+		_, err := r.client.Collections.Delete(ctx, &qdrant.DeleteCollection{CollectionName: name})
+		if err != nil {
+			return fmt.Errorf("failed to delete collection %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// InVersion returns a repository that reads and writes only the collections of the version
+func (r *repository) InVersion(version CollectionVersion) IQdrantRepository {
+	pinned := *r
+	pinned.collectionName = version.Users
+	pinned.passagesCollection = version.Passages
+	pinned.pinned = true
+	return &pinned
+}
@@ -1,21 +1,48 @@
 package dto
 
 type CreateChatRequest struct {
-	Query     string   `json:"query" binding:"required" example:"Who are you having lasagna with today and why?"`
-	Teams     []string `json:"teams,omitempty"`
-	Levels    []string `json:"levels,omitempty"`
-	Locations []string `json:"locations,omitempty"`
-	Limit     uint64   `json:"limit,omitempty"`
+	Query            string   `json:"query" binding:"required" example:"Who are you having lasagna with today and why?"`
+	Teams            []string `json:"teams,omitempty"`
+	Levels           []string `json:"levels,omitempty"`
+	Locations        []string `json:"locations,omitempty"`
+	Limit            uint64   `json:"limit,omitempty"`
+	ExcludeTeams     []string `json:"excludeTeams,omitempty"`
+	ExcludeLevels    []string `json:"excludeLevels,omitempty"`
+	ExcludeLocations []string `json:"excludeLocations,omitempty"`
+	ExcludeUsers     []string `json:"excludeUsers,omitempty"`
+	NegativeQuery    string   `json:"not,omitempty" example:"frontend"`
 }
 
 type CreateChatResponse struct {
-	ID        string   `json:"id"`
-	Title     string   `bson:"title"`
-	Teams     []string `json:"teams,omitempty"`
-	Levels    []string `json:"levels,omitempty"`
-	Locations []string `json:"locations,omitempty"`
-	Limit     uint64   `json:"limit,omitempty"`
-	CreatedAt int64    `json:"created_at"`
+	ID               string   `json:"id"`
+	Title            string   `bson:"title"`
+	Teams            []string `json:"teams,omitempty"`
+	Levels           []string `json:"levels,omitempty"`
+	Locations        []string `json:"locations,omitempty"`
+	Limit            uint64   `json:"limit,omitempty"`
+	ExcludeTeams     []string `json:"excludeTeams,omitempty"`
+	ExcludeLevels    []string `json:"excludeLevels,omitempty"`
+	ExcludeLocations []string `json:"excludeLocations,omitempty"`
+	ExcludeUsers     []string `json:"excludeUsers,omitempty"`
+	NegativeQuery    string   `json:"not,omitempty"`
+	CreatedAt        int64    `json:"created_at"`
+}
+
+// ChatMessageRequest - follow-up turn of a chat, e.g. "someone more senior"
+type ChatMessageRequest struct {
+	Message string `json:"message" binding:"required" example:"same but in London"`
+	Limit   uint64 `json:"limit,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Rerank  string `json:"rerank,omitempty"`
+}
+
+// ChatRefinement - standalone query and filters the follow-up was rewritten to
+type ChatRefinement struct {
+	Message   string              `json:"message"`
+	Query     string              `json:"query"`
+	Teams     []SearchFilterValue `json:"teams"`
+	Levels    []SearchFilterValue `json:"levels"`
+	Locations []SearchFilterValue `json:"locations"`
 }
 
 type GetChatResponse struct {
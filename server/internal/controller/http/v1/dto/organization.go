@@ -102,6 +102,8 @@ type LocationResponse struct {
 
 type PatchOrganizationRequest struct {
 	Title string `json:"title,omitempty" example:"StaffAlienBase"`
+	// LLMModel - one of the allowed models, an empty string switches back to the server default
+	LLMModel *string `json:"llmModel,omitempty" example:"gpt-5-mini"`
 }
 
 type PatchOrganizationResponse struct {
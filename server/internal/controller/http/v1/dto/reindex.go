@@ -0,0 +1,78 @@
+package dto
+
+import "time"
+
+//region ReindexJobStatus
+
+type ReindexJobStatus string
+
+var ReindexJobStatuses = struct {
+	Queued    ReindexJobStatus
+	Running   ReindexJobStatus
+	Completed ReindexJobStatus
+	Failed    ReindexJobStatus
+	Cancelled ReindexJobStatus
+}{
+	Queued:    "queued",
+	Running:   "running",
+	Completed: "completed",
+	Failed:    "failed",
+	Cancelled: "cancelled",
+}
+
+//endregion
+
+// ReindexFailure - user that could not be indexed, the job goes on with the rest and fails
+// at the end unless the failures were accepted
+type ReindexFailure struct {
+	UserID string `json:"userId"`
+	Reason string `json:"reason"`
+}
+
+// ReindexJob - progress of a background reindex. Processed includes failed users
+type ReindexJob struct {
+	ID             string           `json:"id"`
+	OrganizationID string           `json:"organizationId"`
+	Status         ReindexJobStatus `json:"status"`
+	Version        string           `json:"version,omitempty"`
+	// AcceptFailures - commit the version even if some users failed, they keep their live points
+	AcceptFailures bool             `json:"acceptFailures"`
+	Total          int              `json:"total"`
+	Processed      int              `json:"processed"`
+	Failed         int              `json:"failed"`
+	Failures       []ReindexFailure `json:"failures"`
+	Error          string           `json:"error,omitempty"`
+	StartedAt      time.Time        `json:"startedAt"`
+	FinishedAt     *time.Time       `json:"finishedAt,omitempty"`
+}
+
+// DriftReport - differences between MongoDB users and Qdrant points of an organization.
+// Orphaned points belong to deleted or removed users, stale ones were built from an outdated description, team, level or location
+type DriftReport struct {
+	OrganizationID string           `json:"organizationId"`
+	Users          int              `json:"users"`
+	Indexed        int              `json:"indexed"`
+	Missing        []string         `json:"missing"`
+	Orphaned       []string         `json:"orphaned"`
+	Stale          []string         `json:"stale"`
+	Repaired       int              `json:"repaired"`
+	RepairFailures []ReindexFailure `json:"repairFailures"`
+}
+
+// EmbeddingModelPoints - live points built with one model version, model is empty for points without a stamp
+type EmbeddingModelPoints struct {
+	Model   string `json:"model"`
+	Version string `json:"version"`
+	Points  int    `json:"points"`
+}
+
+// EmbeddingModelStats - live points of the organization per embedding model version next to the configured model.
+// Mismatch is set while semantic search is disabled until the users are reindexed with the configured model
+type EmbeddingModelStats struct {
+	Model      string                 `json:"model"`
+	Version    string                 `json:"version"`
+	Dimensions int                    `json:"dimensions"`
+	Users      []EmbeddingModelPoints `json:"users"`
+	Passages   []EmbeddingModelPoints `json:"passages"`
+	Mismatch   string                 `json:"mismatch,omitempty"`
+}
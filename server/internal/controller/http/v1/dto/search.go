@@ -10,14 +10,94 @@ type SearchRequest struct {
 	Levels    []string `form:"levels" json:"levels"`
 	Locations []string `form:"locations" json:"locations"`
 	Limit     uint64   `form:"limit,default=10" json:"limit"`
+	Mode      string   `form:"mode" json:"mode"`
+	// ExtractFilters - recognize teams, levels and locations in the query text
+	ExtractFilters bool `form:"extract,default=true" json:"extract"`
+	// Rerank - none, cross_encoder or llm. Empty uses the server default
+	Rerank           string   `form:"rerank" json:"rerank"`
+	ExcludeTeams     []string `form:"excludeTeams" json:"excludeTeams"`
+	ExcludeLevels    []string `form:"excludeLevels" json:"excludeLevels"`
+	ExcludeLocations []string `form:"excludeLocations" json:"excludeLocations"`
+	ExcludeUsers     []string `form:"excludeUsers" json:"excludeUsers"`
+	// NegativeQuery - text the results should not be about, e.g. "frontend"
+	NegativeQuery string `form:"not" json:"not"`
+	// Page - 1-based, Cursor - opaque continuation from SearchPage.NextCursor
+	Page   uint64 `form:"page" json:"page"`
+	Cursor string `form:"cursor" json:"cursor"`
+	// Diversity - MMR lambda in (0, 1], nil uses the server default
+	Diversity      *float64 `form:"diversity" json:"diversity"`
+	MaxPerTeam     int      `form:"maxPerTeam" json:"maxPerTeam"`
+	MaxPerLocation int      `form:"maxPerLocation" json:"maxPerLocation"`
+	// Facets - count candidates per team, level and location, sent as a 'facets' event
+	Facets bool `form:"facets,default=true" json:"facets"`
+	// Aggregate - rank by description passages: none, max or sum. Empty uses the server default
+	Aggregate string `form:"aggregate" json:"aggregate"`
+}
+
+// PassageHighlight - part of the description that matched the query
+type PassageHighlight struct {
+	Text  string  `json:"text"`
+	Score float32 `json:"score"`
+}
+
+type FacetCount struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets - "12 matches in Engineering, 3 in Design" before the filters are applied
+type SearchFacets struct {
+	Teams     []FacetCount `json:"teams"`
+	Levels    []FacetCount `json:"levels"`
+	Locations []FacetCount `json:"locations"`
+}
+
+// SearchPage - last event of a paged search
+type SearchPage struct {
+	Offset     uint64 `json:"offset"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 type SearchResultWithUser struct {
-	Score float32     `json:"score"`
-	User  *model.User `json:"user"`
+	Score       float32            `json:"score"`
+	RerankScore *float32           `json:"rerankScore,omitempty"`
+	Highlights  []PassageHighlight `json:"highlights,omitempty"`
+	User        *model.User        `json:"user"`
+}
+
+type SimilarUsersResponse struct {
+	Results []SearchResultWithUser `json:"results"`
+}
+
+// DescriptionDelta - next chunk of the assessment JSON of a result, chunks of a user arrive in order.
+// A new attempt starts over after the model returned a malformed assessment
+type DescriptionDelta struct {
+	UserID  string `json:"userId"`
+	Attempt int    `json:"attempt"`
+	Delta   string `json:"delta"`
+}
+
+// DescriptionDone - last event of an assessment, Assessment is empty when none could be generated
+type DescriptionDone struct {
+	UserID     string                 `json:"userId"`
+	Assessment *model.MatchAssessment `json:"assessment,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+type SearchFilterValue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ExtractedFilters - filters recognized in the query text, sent to the UI so they can be undone
+type ExtractedFilters struct {
+	Query     string              `json:"query"`
+	Teams     []SearchFilterValue `json:"teams"`
+	Levels    []SearchFilterValue `json:"levels"`
+	Locations []SearchFilterValue `json:"locations"`
 }
 
-type SearchResultWithUserAndDescription struct {
-	*SearchResultWithUser
-	Description string `json:"description,omitempty"`
+func (f ExtractedFilters) IsEmpty() bool {
+	return len(f.Teams) == 0 && len(f.Levels) == 0 && len(f.Locations) == 0
 }
@@ -5,13 +5,36 @@ import (
 )
 
 const (
-	ReIndex = "/reindex"
+	ReIndex         = "/reindex"
+	ReindexJob      = ReIndex + "/:jobId"
+	IndexDrift      = ReIndex + "/drift"
+	RepairDrift     = IndexDrift + "/repair"
+	IndexVersions   = ReIndex + "/versions"
+	ActivateVersion = IndexVersions + "/:version/activate"
+	IndexModels     = ReIndex + "/models"
+	ConfirmModel    = IndexModels + "/confirm"
 )
 
 type IQdrantController interface {
 	ReIndexReq(c *gin.Context)
+	GetReindexJob(c *gin.Context)
+	CancelReindexJob(c *gin.Context)
+	CheckIndexDrift(c *gin.Context)
+	RepairIndexDrift(c *gin.Context)
+	ListIndexVersions(c *gin.Context)
+	ActivateIndexVersion(c *gin.Context)
+	GetEmbeddingModelStats(c *gin.Context)
+	ConfirmEmbeddingModel(c *gin.Context)
 }
 
 func RegisterQdrantRoutes(g *gin.RouterGroup, securityHandler gin.HandlerFunc, service IQdrantController) {
 	g.POST(ReIndex, securityHandler, service.ReIndexReq)
+	g.GET(ReindexJob, securityHandler, service.GetReindexJob)
+	g.DELETE(ReindexJob, securityHandler, service.CancelReindexJob)
+	g.GET(IndexDrift, securityHandler, service.CheckIndexDrift)
+	g.POST(RepairDrift, securityHandler, service.RepairIndexDrift)
+	g.GET(IndexVersions, securityHandler, service.ListIndexVersions)
+	g.POST(ActivateVersion, securityHandler, service.ActivateIndexVersion)
+	g.GET(IndexModels, securityHandler, service.GetEmbeddingModelStats)
+	g.POST(ConfirmModel, securityHandler, service.ConfirmEmbeddingModel)
 }
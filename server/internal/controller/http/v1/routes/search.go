@@ -9,14 +9,22 @@ import (
 )
 
 const (
-	Search = "/search"
+	Search       = "/search"
+	SimilarUsers = "/users/:id/similar"
+	ChatMessage  = "/chat/:id/message"
 )
 
 type ISearchService interface {
 	Search(ctx *gin.Context)
+	SimilarUsers(ctx *gin.Context)
+	ChatMessage(ctx *gin.Context)
 }
 
 func RegisterSearchRoutes(g *gin.RouterGroup, service ISearchService, sec gin.HandlerFunc, rds *redis.Client) {
 	g.OPTIONS(Search, lib.Preflight)
 	g.GET(Search, rateLimit.RedisRateLimit(rds, 10, time.Minute, Search), sec, service.Search)
+	g.OPTIONS(SimilarUsers, lib.Preflight)
+	g.GET(SimilarUsers, rateLimit.RedisRateLimit(rds, 10, time.Minute, SimilarUsers), sec, service.SimilarUsers)
+	g.OPTIONS(ChatMessage, lib.Preflight)
+	g.POST(ChatMessage, rateLimit.RedisRateLimit(rds, 10, time.Minute, ChatMessage), sec, service.ChatMessage)
 }
@@ -16,7 +16,20 @@ type Chat struct {
 }
 
 type Message struct {
-	Role      string    `bson:"role" json:"role"`
-	Content   bson.M    `bson:"content" json:"content"`
-	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+	Role    string `bson:"role" json:"role"`
+	Content bson.M `bson:"content" json:"content"`
+	// Assessment - why the suggested user fits the search, set on assistant messages with a user
+	Assessment *MatchAssessment `bson:"assessment,omitempty" json:"assessment,omitempty"`
+	Timestamp  time.Time        `bson:"timestamp" json:"timestamp"`
+}
+
+// MatchAssessment - structured explanation of a search match
+type MatchAssessment struct {
+	// FitScore - from 0 (does not fit) to 1 (perfect person to ask)
+	FitScore        float32  `bson:"fitScore" json:"fitScore"`
+	Strengths       []string `bson:"strengths" json:"strengths"`
+	Gaps            []string `bson:"gaps" json:"gaps"`
+	OpeningQuestion string   `bson:"openingQuestion" json:"openingQuestion"`
+	// ContactChannel - one of the channels of the user contact
+	ContactChannel ContactChannel `bson:"contactChannel" json:"contactChannel"`
 }
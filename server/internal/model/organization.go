@@ -52,6 +52,8 @@ type Organization struct {
 	Semantic OrganizationSemantic `bson:"semantic" json:"semantic"`
 	Plan     OrganizationPlanType `bson:"plan" json:"plan"`
 	Status   OrganizationStatus   `bson:"status" json:"status"`
+	// LLMModel - chat model for descriptions and reranking, empty uses the server default
+	LLMModel string `bson:"llmModel,omitempty" json:"llmModel,omitempty"`
 }
 
 type OrganizationSemantic struct {
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+//region OutboxStatus
+
+type OutboxStatus string
+
+var OutboxStatuses = struct {
+	PENDING OutboxStatus
+	FAILED  OutboxStatus
+}{
+	PENDING: "PENDING",
+	FAILED:  "FAILED",
+}
+
+//endregion
+
+// OutboxEvent - the user changed in Mongo and has to be synced to Qdrant.
+// The worker indexes the current state of the user, so repeated events are harmless
+type OutboxEvent struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"userId"`
+	Status      OutboxStatus       `bson:"status" json:"status"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	LastError   string             `bson:"last_error,omitempty" json:"lastError,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"createdAt"`
+	AvailableAt time.Time          `bson:"available_at" json:"availableAt"`
+}
@@ -48,6 +48,26 @@ type UserSemantic = struct {
 	Location    primitive.ObjectID `json:"location" bson:"location"`
 }
 
+//region ContactChannel
+
+type ContactChannel string
+
+var ContactChannels = struct {
+	Slack     ContactChannel
+	Telephone ContactChannel
+	Email     ContactChannel
+	Telegram  ContactChannel
+	WhatsApp  ContactChannel
+}{
+	Slack:     "slack",
+	Telephone: "telephone",
+	Email:     "email",
+	Telegram:  "telegram",
+	WhatsApp:  "whatsapp",
+}
+
+//endregion
+
 type UserContact struct {
 	Slack     string `json:"slack" bson:"slack"`
 	Telephone string `json:"telephone" bson:"telephone"`
@@ -56,6 +76,26 @@ type UserContact struct {
 	WhatsApp  string `json:"whatsapp" bson:"whatsapp"`
 }
 
+// Channels returns the channels the user filled in
+func (c UserContact) Channels() []ContactChannel {
+	channels := make([]ContactChannel, 0, 5)
+	for _, contact := range []struct {
+		channel ContactChannel
+		value   string
+	}{
+		{ContactChannels.Slack, c.Slack},
+		{ContactChannels.Telephone, c.Telephone},
+		{ContactChannels.Email, c.Email},
+		{ContactChannels.Telegram, c.Telegram},
+		{ContactChannels.WhatsApp, c.WhatsApp},
+	} {
+		if contact.value != "" {
+			channels = append(channels, contact.channel)
+		}
+	}
+	return channels
+}
+
 type User struct {
 	ID               primitive.ObjectID `json:"_id" bson:"_id"`
 	Email            string             `json:"email" bson:"email"`
@@ -69,4 +109,7 @@ type User struct {
 	AvatarID         primitive.ObjectID `json:"avatarId" bson:"avatarId"`
 	OrganizationID   primitive.ObjectID `json:"organizationId" bson:"organizationId"`
 	OrganizationRole OrganizationRole   `json:"organizationRole" bson:"organizationRole"`
+	// SyncToken is renewed by every write and cleared once the user is synced to Qdrant.
+	// The marker is a part of the same single-document update, so a change is never lost without a transaction
+	SyncToken primitive.ObjectID `json:"-" bson:"syncToken,omitempty"`
 }
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sashabaranov/go-openai/jsonschema"
+	"semki/internal/model"
+	"slices"
+	"strings"
+)
+
+// maxAssessmentAttempts - malformed assessments are asked for again, the model sees what was wrong
+const maxAssessmentAttempts = 3
+
+// assessmentChannels - channels the model may recommend, the account email when the user filled in none
+func assessmentChannels(user model.User) []model.ContactChannel {
+	channels := user.Contact.Channels()
+	if len(channels) == 0 {
+		channels = append(channels, model.ContactChannels.Email)
+	}
+	return channels
+}
+
+// assessmentSchema constrains the output to a model.MatchAssessment with one of the channels of the user.
+// Strict schemas need every property required and no additional ones
+func assessmentSchema(channels []model.ContactChannel) *jsonschema.Definition {
+	enum := make([]string, len(channels))
+	for i, channel := range channels {
+		enum[i] = string(channel)
+	}
+	list := func(description string) jsonschema.Definition {
+		return jsonschema.Definition{Type: jsonschema.Array, Description: description, Items: &jsonschema.Definition{Type: jsonschema.String}}
+	}
+
+	return &jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"fitScore":        {Type: jsonschema.Number, Description: "How well the user fits the query, from 0 (not at all) to 1 (perfect person to ask)"},
+			"strengths":       list("Reasons the user fits the query, short phrases"),
+			"gaps":            list("What the user lacks for the query, short phrases, may be empty"),
+			"openingQuestion": {Type: jsonschema.String, Description: "A first question to ask the user about the query"},
+			"contactChannel":  {Type: jsonschema.String, Enum: enum, Description: "Best channel to reach the user about the query"},
+		},
+		Required:             []string{"fitScore", "strengths", "gaps", "openingQuestion", "contactChannel"},
+		AdditionalProperties: false,
+	}
+}
+
+// parseAssessment checks the output against the schema and the rules the schema can't express
+func parseAssessment(content string, channels []model.ContactChannel) (model.MatchAssessment, error) {
+	var assessment model.MatchAssessment
+	if err := assessmentSchema(channels).Unmarshal(content, &assessment); err != nil {
+		return model.MatchAssessment{}, fmt.Errorf("output does not match the schema: %w", err)
+	}
+
+	if assessment.FitScore < 0 || assessment.FitScore > 1 {
+		return model.MatchAssessment{}, fmt.Errorf("fitScore %v is outside of 0..1", assessment.FitScore)
+	}
+	assessment.Strengths = trimmedPhrases(assessment.Strengths)
+	assessment.Gaps = trimmedPhrases(assessment.Gaps)
+	assessment.OpeningQuestion = strings.TrimSpace(assessment.OpeningQuestion)
+	if len(assessment.Strengths) == 0 && len(assessment.Gaps) == 0 {
+		return model.MatchAssessment{}, errors.New("strengths and gaps are both empty")
+	}
+	if assessment.OpeningQuestion == "" {
+		return model.MatchAssessment{}, errors.New("openingQuestion is empty")
+	}
+	if !slices.Contains(channels, assessment.ContactChannel) {
+		return model.MatchAssessment{}, fmt.Errorf("contactChannel %q is not a channel of the user", assessment.ContactChannel)
+	}
+	return assessment, nil
+}
+
+func trimmedPhrases(phrases []string) []string {
+	trimmed := make([]string, 0, len(phrases))
+	for _, phrase := range phrases {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			trimmed = append(trimmed, phrase)
+		}
+	}
+	return trimmed
+}
@@ -62,11 +62,16 @@ func (s *chatService) CreateChat(c *gin.Context) {
 			{
 				Role: openai.ChatMessageRoleUser,
 				Content: bson.M{
-					"title":     req.Query,
-					"teams":     req.Teams,
-					"levels":    req.Levels,
-					"locations": req.Locations,
-					"limit":     req.Limit,
+					"title":             req.Query,
+					"teams":             req.Teams,
+					"levels":            req.Levels,
+					"locations":         req.Locations,
+					"limit":             req.Limit,
+					"exclude_teams":     req.ExcludeTeams,
+					"exclude_levels":    req.ExcludeLevels,
+					"exclude_locations": req.ExcludeLocations,
+					"exclude_users":     req.ExcludeUsers,
+					"not":               req.NegativeQuery,
 				},
 				Timestamp: time.Now(),
 			},
@@ -79,13 +84,18 @@ func (s *chatService) CreateChat(c *gin.Context) {
 	}
 
 	response := dto.CreateChatResponse{
-		ID:        chat.ID.Hex(),
-		Title:     chat.Title,
-		Teams:     req.Teams,
-		Levels:    req.Levels,
-		Locations: req.Locations,
-		Limit:     req.Limit,
-		CreatedAt: chat.CreatedAt.Unix(),
+		ID:               chat.ID.Hex(),
+		Title:            chat.Title,
+		Teams:            req.Teams,
+		Levels:           req.Levels,
+		Locations:        req.Locations,
+		Limit:            req.Limit,
+		ExcludeTeams:     req.ExcludeTeams,
+		ExcludeLevels:    req.ExcludeLevels,
+		ExcludeLocations: req.ExcludeLocations,
+		ExcludeUsers:     req.ExcludeUsers,
+		NegativeQuery:    req.NegativeQuery,
+		CreatedAt:        chat.CreatedAt.Unix(),
 	}
 
 	c.JSON(http.StatusCreated, response)
@@ -178,6 +188,10 @@ func (s *chatService) GetChat(c *gin.Context) {
 				content[k] = v
 			}
 		}
+		// Flat like the streamed results, so the client reads both the same way
+		if msg.Assessment != nil {
+			content["assessment"] = msg.Assessment
+		}
 		messages = append(messages, content)
 	}
 
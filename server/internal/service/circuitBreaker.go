@@ -0,0 +1,78 @@
+package service
+
+import (
+	"semki/pkg/telemetry"
+	"sync"
+	"time"
+)
+
+// circuitBreaker fails calls fast once a dependency failed threshold times in a row.
+// After cooldown a single probe call is let through, its result closes or reopens the circuit
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker - a threshold of 0 never opens the circuit
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{name: name, threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may be made, every allowed call must end with success, failure or release
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open() {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.open() {
+		telemetry.Log.Info(b.name + " recovered, circuit closed")
+	}
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.open() {
+		if b.failures == b.threshold {
+			telemetry.Log.Warn(b.name + " is failing, circuit opened")
+		}
+		b.openedAt = time.Now()
+	}
+}
+
+// release ends a call that says nothing about the dependency, e.g. one cancelled by the caller
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// open - callers hold mu
+func (b *circuitBreaker) open() bool {
+	return b.threshold > 0 && b.failures >= b.threshold
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"semki/pkg/telemetry"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	breaker := newCircuitBreaker("test", 2, 20*time.Millisecond)
+
+	assert.True(t, breaker.allow())
+	breaker.failure()
+	assert.True(t, breaker.allow())
+	breaker.failure()
+	assert.False(t, breaker.allow(), "circuit opens after threshold failures")
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, breaker.allow(), "one probe after cooldown")
+	assert.False(t, breaker.allow(), "only one probe at a time")
+	breaker.failure()
+	assert.False(t, breaker.allow(), "failed probe reopens the circuit")
+
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, breaker.allow())
+	breaker.success()
+	assert.True(t, breaker.allow())
+	assert.True(t, breaker.allow())
+}
+
+func TestCircuitBreakerReleaseKeepsCircuitOpen(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	breaker := newCircuitBreaker("test", 1, 10*time.Millisecond)
+	breaker.failure()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, breaker.allow())
+	breaker.release()
+	assert.True(t, breaker.allow(), "a released probe lets the next call probe")
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := newCircuitBreaker("test", 0, time.Minute)
+	for range 10 {
+		breaker.failure()
+	}
+	assert.True(t, breaker.allow())
+}
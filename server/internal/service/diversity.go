@@ -0,0 +1,102 @@
+package service
+
+import (
+	"math"
+	"semki/internal/adapter/qdrant"
+)
+
+// diversifyResults picks limit results with maximal marginal relevance:
+// lambda * relevance - (1 - lambda) * max similarity to already picked results.
+// Relevance is min-max normalized, so RRF and cosine scores behave the same.
+// Candidates over the team or location cap are skipped, the list may get shorter than limit
+func diversifyResults(results []qdrant.VectorSearchResult, diversity qdrant.Diversity, limit int) []qdrant.VectorSearchResult {
+	if len(results) == 0 || limit <= 0 {
+		return results
+	}
+
+	relevance := normalizedScores(results)
+	lambda := diversity.Lambda
+	if lambda <= 0 || lambda > 1 {
+		// Caps only, keep the relevance order
+		lambda = 1
+	}
+
+	picked := make([]qdrant.VectorSearchResult, 0, limit)
+	pickedIdx := make([]int, 0, limit)
+	used := make([]bool, len(results))
+	perTeam := make(map[string]int)
+	perLocation := make(map[string]int)
+
+	for len(picked) < limit {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i, candidate := range results {
+			if used[i] {
+				continue
+			}
+			if diversity.MaxPerTeam > 0 && perTeam[candidate.Team] >= diversity.MaxPerTeam {
+				continue
+			}
+			if diversity.MaxPerLocation > 0 && perLocation[candidate.Location] >= diversity.MaxPerLocation {
+				continue
+			}
+
+			redundancy := 0.0
+			for _, j := range pickedIdx {
+				redundancy = math.Max(redundancy, cosineSimilarity(candidate.Vector, results[j].Vector))
+			}
+
+			score := lambda*relevance[i] - (1-lambda)*redundancy
+			if score > bestScore {
+				best = i
+				bestScore = score
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		used[best] = true
+		pickedIdx = append(pickedIdx, best)
+		picked = append(picked, results[best])
+		perTeam[results[best].Team]++
+		perLocation[results[best].Location]++
+	}
+
+	return picked
+}
+
+func normalizedScores(results []qdrant.VectorSearchResult) []float64 {
+	minScore, maxScore := math.Inf(1), math.Inf(-1)
+	for _, r := range results {
+		minScore = math.Min(minScore, float64(r.Score))
+		maxScore = math.Max(maxScore, float64(r.Score))
+	}
+
+	scores := make([]float64, len(results))
+	for i, r := range results {
+		if maxScore > minScore {
+			scores[i] = (float64(r.Score) - minScore) / (maxScore - minScore)
+		} else {
+			scores[i] = 1
+		}
+	}
+	return scores
+}
+
+// cosineSimilarity is 0 when a vector is missing, so such candidates are never penalized
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
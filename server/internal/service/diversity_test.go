@@ -0,0 +1,40 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"semki/internal/adapter/qdrant"
+	"testing"
+)
+
+func TestDiversifyResultsPrefersDifferentVectors(t *testing.T) {
+	results := []qdrant.VectorSearchResult{
+		{UserID: "a", Score: 0.95, Vector: []float32{1, 0}},
+		{UserID: "b", Score: 0.94, Vector: []float32{1, 0.01}},
+		{UserID: "c", Score: 0.80, Vector: []float32{0, 1}},
+	}
+
+	relevant := diversifyResults(results, qdrant.Diversity{Lambda: 1}, 2)
+	assert.Equal(t, []string{"a", "b"}, userIDs(relevant))
+
+	diverse := diversifyResults(results, qdrant.Diversity{Lambda: 0.5}, 2)
+	assert.Equal(t, []string{"a", "c"}, userIDs(diverse))
+}
+
+func TestDiversifyResultsCapsPerTeam(t *testing.T) {
+	results := []qdrant.VectorSearchResult{
+		{UserID: "a", Score: 0.9, Team: "x"},
+		{UserID: "b", Score: 0.8, Team: "x"},
+		{UserID: "c", Score: 0.7, Team: "y"},
+	}
+
+	capped := diversifyResults(results, qdrant.Diversity{MaxPerTeam: 1}, 3)
+	assert.Equal(t, []string{"a", "c"}, userIDs(capped))
+}
+
+func userIDs(results []qdrant.VectorSearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.UserID
+	}
+	return ids
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"semki/internal/utils/jwtUtils"
+	"semki/pkg/lib"
+	"semki/pkg/telemetry"
+	"sort"
+)
+
+// CheckIndexDrift godoc
+//
+//	@Summary		Compare the index with MongoDB
+//	@Description	Lists users missing from Qdrant, points of deleted users and points built from outdated data. Users with pending outbox events show up until the worker syncs them.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Success		200	{object}	dto.DriftReport
+//	@Failure		500	{object}	lib.ErrorResponse	"Failed to check drift"
+//	@Router			/api/v1/reindex/drift [get]
+//	@Security		BearerAuth
+func (s *qdrantService) CheckIndexDrift(c *gin.Context) {
+	s.handleDrift(c, false)
+}
+
+// RepairIndexDrift godoc
+//
+//	@Summary		Repair the index
+//	@Description	Indexes missing and stale users and removes orphaned points. Failures are listed in the report.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Success		200	{object}	dto.DriftReport
+//	@Failure		500	{object}	lib.ErrorResponse	"Failed to check drift"
+//	@Router			/api/v1/reindex/drift/repair [post]
+//	@Security		BearerAuth
+func (s *qdrantService) RepairIndexDrift(c *gin.Context) {
+	s.handleDrift(c, true)
+}
+
+func (s *qdrantService) handleDrift(c *gin.Context, repair bool) {
+	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
+	if !ok || userClaimsRaw == nil {
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
+		return
+	}
+	claims := userClaimsRaw.(*jwtUtils.UserClaims)
+
+	report, err := s.CheckDrift(c.Request.Context(), claims.OrganizationID, repair)
+	if err != nil {
+		lib.ResponseInternalServerError(c, err, "Failed to check index drift")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// CheckDrift compares users of the organization with its Qdrant points, repair fixes every difference
+func (s *qdrantService) CheckDrift(ctx context.Context, organizationID primitive.ObjectID, repair bool) (dto.DriftReport, error) {
+	report := dto.DriftReport{
+		OrganizationID: organizationID.Hex(),
+		Missing:        []string{},
+		Orphaned:       []string{},
+		Stale:          []string{},
+		RepairFailures: []dto.ReindexFailure{},
+	}
+
+	indexed, err := s.repo.ScrollIndexedUsers(ctx, organizationID.Hex())
+	if err != nil {
+		return report, err
+	}
+	users, err := s.organizationUsers(ctx, organizationID)
+	if err != nil {
+		return report, err
+	}
+
+	expected := make(map[string]*model.User, len(users))
+	for _, user := range users {
+		if user.Status != model.UserStatuses.DELETED {
+			expected[user.ID.Hex()] = user
+		}
+	}
+	report.Users = len(expected)
+	report.Indexed = len(indexed)
+	report.Missing, report.Orphaned, report.Stale = diffIndex(expected, indexed)
+
+	if !repair {
+		return report, nil
+	}
+	for _, userID := range append(append([]string{}, report.Missing...), report.Stale...) {
+		recordRepair(&report, userID, s.IndexUser(ctx, expected[userID]))
+	}
+	for _, userID := range report.Orphaned {
+		recordRepair(&report, userID, s.repo.DeleteUser(ctx, userID))
+	}
+	return report, nil
+}
+
+func recordRepair(report *dto.DriftReport, userID string, err error) {
+	if err != nil {
+		telemetry.Log.Error("Failed to repair index of user " + userID + ": " + err.Error())
+		report.RepairFailures = append(report.RepairFailures, dto.ReindexFailure{UserID: userID, Reason: err.Error()})
+		return
+	}
+	report.Repaired++
+}
+
+func (s *qdrantService) organizationUsers(ctx context.Context, organizationID primitive.ObjectID) ([]*model.User, error) {
+	const limit = 100
+	all := make([]*model.User, 0)
+	for page := 1; ; page++ {
+		users, total, err := s.userRepo.GetUsersByOrganization(ctx, organizationID, "", page, limit)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, users...)
+		if len(users) == 0 || int64(page*limit) >= total {
+			return all, nil
+		}
+	}
+}
+
+// diffIndex - missing: expected without a point, orphaned: points without an expected user,
+// stale: points whose content hash differs from the current user
+func diffIndex(expected map[string]*model.User, indexed []qdrant.IndexedUser) (missing []string, orphaned []string, stale []string) {
+	missing, orphaned, stale = []string{}, []string{}, []string{}
+	seen := make(map[string]struct{}, len(indexed))
+	for _, point := range indexed {
+		seen[point.UserID] = struct{}{}
+		user, ok := expected[point.UserID]
+		if !ok {
+			orphaned = append(orphaned, point.UserID)
+		} else if point.ContentHash != qdrant.UserContentHash(user) {
+			stale = append(stale, point.UserID)
+		}
+	}
+	for userID := range expected {
+		if _, ok := seen[userID]; !ok {
+			missing = append(missing, userID)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(orphaned)
+	sort.Strings(stale)
+	return missing, orphaned, stale
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/model"
+	"testing"
+)
+
+func TestDiffIndex(t *testing.T) {
+	user := func(description string) *model.User {
+		u := &model.User{ID: primitive.NewObjectID(), OrganizationID: primitive.NewObjectID()}
+		u.Semantic.Description = description
+		return u
+	}
+	current := user("kafka")
+	changed := user("go")
+	missing := user("rust")
+	expected := map[string]*model.User{
+		current.ID.Hex(): current,
+		changed.ID.Hex(): changed,
+		missing.ID.Hex(): missing,
+	}
+
+	outdated := *changed
+	outdated.Semantic.Description = "java"
+	indexed := []qdrant.IndexedUser{
+		{UserID: current.ID.Hex(), ContentHash: qdrant.UserContentHash(current)},
+		{UserID: changed.ID.Hex(), ContentHash: qdrant.UserContentHash(&outdated)},
+		{UserID: "deleted", ContentHash: "hash"},
+	}
+
+	gotMissing, gotOrphaned, gotStale := diffIndex(expected, indexed)
+	assert.Equal(t, []string{missing.ID.Hex()}, gotMissing)
+	assert.Equal(t, []string{"deleted"}, gotOrphaned)
+	assert.Equal(t, []string{changed.ID.Hex()}, gotStale)
+}
@@ -1,18 +1,68 @@
 package service
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"github.com/gin-gonic/gin"
 	"net/http"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/utils/config"
+	"semki/pkg/lib"
 	"semki/pkg/telemetry"
-	"time"
+	"sync/atomic"
 )
 
+//region Errors
+
+var (
+	// ErrEmbedderTimeout - the embedder did not answer in time
+	ErrEmbedderTimeout = errors.New("embedder timed out")
+	// ErrEmbedderUnavailable - the embedder is down, overloaded or the circuit is open
+	ErrEmbedderUnavailable = errors.New("embedder unavailable")
+	// ErrEmbedderBadInput - the embedder rejected the request, retrying will not help
+	ErrEmbedderBadInput = errors.New("embedder rejected input")
+)
+
+// EmbedderErrorStatus maps embedder errors to the HTTP status of the failed request.
+// Vectors of another model can't be searched until a reindex, so the search is unavailable too
+func EmbedderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrEmbedderTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrEmbedderUnavailable), errors.Is(err, qdrant.ErrModelMismatch):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrEmbedderBadInput):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// responseEmbedderError answers with the status of the embedder error, other errors are internal
+func responseEmbedderError(c *gin.Context, err error, message string) {
+	switch status := EmbedderErrorStatus(err); status {
+	case http.StatusInternalServerError:
+		lib.ResponseInternalServerError(c, err, message)
+	case http.StatusBadRequest:
+		lib.ResponseBadRequest(c, err, message)
+	default:
+		telemetry.Log.Error(message)
+		c.JSON(status, lib.ErrorResponse{Message: message})
+	}
+}
+
+//endregion
+
 type IEmbedderService interface {
-	Embed(text string) ([]float32, error)
-	EmbedBatch(texts []string) ([][]float32, error)
-	EmbedBatchWithIDs(texts []TextWithID) ([]EmbeddingWithID, error)
+	// ModelID - model the vectors are built with, vectors of different models are not comparable
+	ModelID() string
+	// Dimensions - length of every vector, 0 when not known yet
+	Dimensions() int
+	Embed(ctx context.Context, text string) ([]float32, error)
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	EmbedBatchWithIDs(ctx context.Context, texts []TextWithID) ([]EmbeddingWithID, error)
+	Rerank(ctx context.Context, query string, texts []string) ([]float32, error)
 }
 
 type EmbeddingResponse struct {
@@ -26,6 +76,11 @@ type EmbeddingWithIDResponse struct {
 	} `json:"embeddings"`
 }
 
+// RerankResponse - cross-encoder relevance of every text to the query, in request order
+type RerankResponse struct {
+	Scores []float32 `json:"scores"`
+}
+
 type TextWithID struct {
 	ID   string `json:"id"`
 	Text string `json:"text"`
@@ -37,26 +92,32 @@ type EmbeddingWithID struct {
 	Embedding []float32
 }
 
-// embedderService handles text embedding operations
+// embedderService handles text embedding operations of the self-hosted embedder
 type embedderService struct {
-	embedderURL string
-	httpClient  *http.Client
+	transport  *embedderTransport
+	model      string
+	dimensions atomic.Int64
 }
 
-// NewEmbedderService creates a new embedder service instance
-func NewEmbedderService(embedderURL string) IEmbedderService {
+// NewEmbedderService creates a new embedder service instance, dimensions are learned from the first response
+func NewEmbedderService(cfg config.EmbedderConfig) IEmbedderService {
 	return &embedderService{
-		embedderURL: embedderURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second, // Default timeout, will be adjusted per request
-		},
+		transport: newEmbedderTransport("embedder", cfg.Url, nil, cfg),
+		model:     cfg.Model,
 	}
 }
 
+func (s *embedderService) ModelID() string {
+	return s.model
+}
+
+func (s *embedderService) Dimensions() int {
+	return int(s.dimensions.Load())
+}
+
 // Embed embeds a single text
-func (s *embedderService) Embed(text string) ([]float32, error) {
-	telemetry.Log.Info("EMBED" + text)
-	embeddings, err := s.EmbedBatch([]string{text})
+func (s *embedderService) Embed(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := s.EmbedBatch(ctx, []string{text})
 	if err != nil {
 		return nil, err
 	}
@@ -67,48 +128,15 @@ func (s *embedderService) Embed(text string) ([]float32, error) {
 }
 
 // EmbedBatch embeds multiple texts
-func (s *embedderService) EmbedBatch(texts []string) ([][]float32, error) {
+func (s *embedderService) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	telemetry.Log.Info(fmt.Sprintf("working with texts %d", len(texts)))
 
-	// Prepare request body
-	requestBody := map[string][]string{
-		"texts": texts,
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create request with dynamic timeout
-	req, err := http.NewRequest("POST", s.embedderURL+"/embed", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	// Create client with dynamic timeout based on text count
-	client := &http.Client{
-		Timeout: time.Duration(len(texts)*5) * time.Second,
-	}
-
-	// Send request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	// Parse response
 	var response EmbeddingResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := s.transport.post(ctx, "/embed", map[string][]string{"texts": texts}, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) > 0 {
+		s.dimensions.Store(int64(len(response.Embeddings[0])))
 	}
 
 	telemetry.Log.Info(fmt.Sprintf("✅ embeddings done! %d", len(response.Embeddings)))
@@ -117,48 +145,12 @@ func (s *embedderService) EmbedBatch(texts []string) ([][]float32, error) {
 }
 
 // EmbedBatchWithIDs embeds multiple texts with their IDs
-func (s *embedderService) EmbedBatchWithIDs(texts []TextWithID) ([]EmbeddingWithID, error) {
+func (s *embedderService) EmbedBatchWithIDs(ctx context.Context, texts []TextWithID) ([]EmbeddingWithID, error) {
 	telemetry.Log.Info(fmt.Sprintf("working with texts %d", len(texts)))
 
-	// Prepare request body
-	requestBody := map[string][]TextWithID{
-		"texts": texts,
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create request with dynamic timeout
-	req, err := http.NewRequest("POST", s.embedderURL+"/embed_with_ids", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	// Create client with dynamic timeout based on text count
-	client := &http.Client{
-		Timeout: time.Duration(len(texts)*5) * time.Second,
-	}
-
-	// Send request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
-	// Parse response
 	var response EmbeddingWithIDResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := s.transport.post(ctx, "/embed_with_ids", map[string][]TextWithID{"texts": texts}, &response); err != nil {
+		return nil, err
 	}
 
 	// Convert to result format
@@ -174,3 +166,24 @@ func (s *embedderService) EmbedBatchWithIDs(texts []TextWithID) ([]EmbeddingWith
 
 	return result, nil
 }
+
+// Rerank scores query-text pairs with the cross-encoder of the embedder
+func (s *embedderService) Rerank(ctx context.Context, query string, texts []string) ([]float32, error) {
+	telemetry.Log.Info(fmt.Sprintf("reranking texts %d", len(texts)))
+
+	requestBody := map[string]interface{}{
+		"query": query,
+		"texts": texts,
+	}
+
+	var response RerankResponse
+	if err := s.transport.post(ctx, "/rerank", requestBody, &response); err != nil {
+		return nil, err
+	}
+
+	if len(response.Scores) != len(texts) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d texts", len(response.Scores), len(texts))
+	}
+
+	return response.Scores, nil
+}
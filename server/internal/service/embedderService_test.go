@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestEmbedder answers /embed with the given statuses in order, the last one repeats
+func newTestEmbedder(t *testing.T, failures int, statuses ...int) (IEmbedderService, *atomic.Int32) {
+	telemetry.Log = zap.NewNop()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		status := statuses[min(n, len(statuses)-1)]
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = fmt.Fprint(w, `{"embeddings": [[0.5, 1]]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return NewEmbedderService(config.EmbedderConfig{
+		Url:             server.URL,
+		Timeout:         time.Second,
+		MaxRetries:      2,
+		RetryBase:       time.Millisecond,
+		BreakerFailures: failures,
+		BreakerCooldown: time.Minute,
+	}), &calls
+}
+
+func TestEmbedderRetriesUnavailable(t *testing.T) {
+	embedder, calls := newTestEmbedder(t, 0, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+
+	vector, err := embedder.Embed(context.Background(), "go")
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0.5, 1}, vector)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestEmbedderDoesNotRetryBadInput(t *testing.T) {
+	embedder, calls := newTestEmbedder(t, 0, http.StatusUnprocessableEntity)
+
+	_, err := embedder.Embed(context.Background(), "go")
+	assert.ErrorIs(t, err, ErrEmbedderBadInput)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestEmbedderCircuitFailsFast(t *testing.T) {
+	embedder, calls := newTestEmbedder(t, 3, http.StatusBadGateway)
+
+	_, err := embedder.Embed(context.Background(), "go")
+	assert.ErrorIs(t, err, ErrEmbedderUnavailable)
+	assert.Equal(t, int32(3), calls.Load())
+
+	_, err = embedder.Embed(context.Background(), "go")
+	assert.ErrorIs(t, err, ErrEmbedderUnavailable)
+	assert.Equal(t, int32(3), calls.Load(), "open circuit must not reach the embedder")
+}
+
+func TestEmbedderHonorsCallerContext(t *testing.T) {
+	embedder, calls := newTestEmbedder(t, 0, http.StatusOK)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := embedder.Embed(ctx, "go")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), calls.Load())
+}
+
+func TestEmbedderErrorStatus(t *testing.T) {
+	assert.Equal(t, http.StatusGatewayTimeout, EmbedderErrorStatus(fmt.Errorf("embedding failed: %w", ErrEmbedderTimeout)))
+	assert.Equal(t, http.StatusServiceUnavailable, EmbedderErrorStatus(fmt.Errorf("embedding failed: %w", ErrEmbedderUnavailable)))
+	assert.Equal(t, http.StatusBadRequest, EmbedderErrorStatus(fmt.Errorf("embedding failed: %w", ErrEmbedderBadInput)))
+	assert.Equal(t, http.StatusInternalServerError, EmbedderErrorStatus(errors.New("qdrant is down")))
+}
+
+func TestEmbedderBackoff(t *testing.T) {
+	for attempt := range 4 {
+		delay := embedderBackoff(attempt, 100*time.Millisecond)
+		full := 100 * time.Millisecond << attempt
+		assert.GreaterOrEqual(t, delay, full/2)
+		assert.Less(t, delay, full)
+	}
+}
+
+func TestModelMismatchMakesSearchUnavailable(t *testing.T) {
+	err := fmt.Errorf("search failed: %w", qdrant.ErrModelMismatch)
+	assert.Equal(t, http.StatusServiceUnavailable, EmbedderErrorStatus(err))
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"time"
+)
+
+// embedderTransport posts JSON to an embedding API through a circuit breaker, retrying failed attempts
+type embedderTransport struct {
+	name       string
+	baseURL    string
+	headers    map[string]string
+	httpClient *http.Client
+	timeout    time.Duration
+	maxRetries int
+	retryBase  time.Duration
+	breaker    *circuitBreaker
+}
+
+func newEmbedderTransport(name string, baseURL string, headers map[string]string, cfg config.EmbedderConfig) *embedderTransport {
+	return &embedderTransport{
+		name:    name,
+		baseURL: baseURL,
+		headers: headers,
+		// Requests are limited by their context, the client is shared to reuse connections
+		httpClient: &http.Client{},
+		timeout:    cfg.Timeout,
+		maxRetries: cfg.MaxRetries,
+		retryBase:  cfg.RetryBase,
+		breaker:    newCircuitBreaker(name, cfg.BreakerFailures, cfg.BreakerCooldown),
+	}
+}
+
+// post sends the request through the circuit breaker. Embedding has no side effects,
+// so timeouts and unavailability are retried with jittered backoff
+func (t *embedderTransport) post(ctx context.Context, path string, body interface{}, response interface{}) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if !t.breaker.allow() {
+			return fmt.Errorf("%w: circuit open", ErrEmbedderUnavailable)
+		}
+
+		err = t.send(ctx, path, jsonBody, response)
+		switch {
+		case err == nil:
+			t.breaker.success()
+			return nil
+		case ctx.Err() != nil:
+			// The caller gave up, that says nothing about the embedder
+			t.breaker.release()
+			return err
+		case errors.Is(err, ErrEmbedderTimeout) || errors.Is(err, ErrEmbedderUnavailable):
+			t.breaker.failure()
+		case errors.Is(err, ErrEmbedderBadInput):
+			// The embedder is up, it just didn't like the request
+			t.breaker.success()
+			return err
+		default:
+			t.breaker.release()
+			return err
+		}
+
+		if attempt >= t.maxRetries {
+			return err
+		}
+		delay := embedderBackoff(attempt, t.retryBase)
+		telemetry.Log.Warn(fmt.Sprintf("%s %s failed, retrying in %s: %s", t.name, path, delay, err))
+		select {
+		case <-ctx.Done():
+			return embedderContextError(ctx)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send makes a single attempt, errors are classified as timeout, unavailable or bad input
+func (t *embedderTransport) send(ctx context.Context, path string, jsonBody []byte, response interface{}) error {
+	attemptCtx := ctx
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodPost, t.baseURL+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return embedderContextError(ctx)
+		}
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return fmt.Errorf("%w: %w", ErrEmbedderTimeout, err)
+		}
+		return fmt.Errorf("%w: %w", ErrEmbedderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: status %d", ErrEmbedderUnavailable, resp.StatusCode)
+	default:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: status %d: %s", ErrEmbedderBadInput, resp.StatusCode, detail)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		if ctx.Err() != nil {
+			return embedderContextError(ctx)
+		}
+		if attemptCtx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrEmbedderTimeout, err)
+		}
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// embedderContextError - a caller deadline is a timeout, a cancellation is passed on as it is
+func embedderContextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrEmbedderTimeout, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+// embedderBackoff doubles base with every attempt, the upper half of the delay is random so retries of concurrent calls spread out
+func embedderBackoff(attempt int, base time.Duration) time.Duration {
+	delay := base << attempt
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + rand.N(delay/2)
+}
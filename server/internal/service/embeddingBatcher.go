@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"semki/internal/utils/config"
+	"slices"
+	"sync"
+	"time"
+)
+
+// embedCall - one text on its way to the embedder, shared by every caller asking for the same text
+type embedCall struct {
+	text    string
+	batch   *embedBatch
+	waiters int
+	done    chan struct{}
+	vector  []float32
+	err     error
+}
+
+// embedBatch - texts sent in one request. Its context is cancelled when no caller waits for any of its texts
+type embedBatch struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiting int
+}
+
+// batchingEmbedder merges concurrent calls into /embed batches. A batch is sent when it reaches
+// maxBatch texts or linger after its first text. Identical texts pending or in flight are embedded once
+type batchingEmbedder struct {
+	IEmbedderService
+	linger   time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	calls   map[string]*embedCall
+	batch   *embedBatch
+	pending []*embedCall
+	timer   *time.Timer
+}
+
+// NewBatchingEmbedder wraps next, a zero linger disables batching
+func NewBatchingEmbedder(next IEmbedderService, cfg config.EmbedderConfig) IEmbedderService {
+	if cfg.BatchLinger <= 0 || cfg.MaxBatch <= 1 {
+		return next
+	}
+	return &batchingEmbedder{
+		IEmbedderService: next,
+		linger:           cfg.BatchLinger,
+		maxBatch:         cfg.MaxBatch,
+		calls:            make(map[string]*embedCall),
+	}
+}
+
+func (b *batchingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := b.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch joins texts to the pending batch, batches of maxBatch or more are sent as they are.
+// A cancelled caller stops waiting, the batch is still sent for the others and cancelled once nobody waits
+func (b *batchingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) >= b.maxBatch {
+		return b.IEmbedderService.EmbedBatch(ctx, texts)
+	}
+
+	calls := b.submit(texts)
+	vectors := make([][]float32, len(texts))
+	for i, call := range calls {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			b.leave(calls[i:])
+			return nil, embedderContextError(ctx)
+		}
+		if call.err != nil {
+			b.leave(calls[i+1:])
+			return nil, call.err
+		}
+		vectors[i] = call.vector
+	}
+	return vectors, nil
+}
+
+func (b *batchingEmbedder) submit(texts []string) []*embedCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	calls := make([]*embedCall, len(texts))
+	for i, text := range texts {
+		if call, ok := b.calls[text]; ok {
+			call.waiters++
+			calls[i] = call
+			continue
+		}
+		if b.batch == nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			b.batch = &embedBatch{ctx: ctx, cancel: cancel}
+		}
+		call := &embedCall{text: text, batch: b.batch, waiters: 1, done: make(chan struct{})}
+		b.batch.waiting++
+		b.calls[text] = call
+		calls[i] = call
+		b.pending = append(b.pending, call)
+
+		if len(b.pending) >= b.maxBatch {
+			go b.send(b.takePending())
+		} else if len(b.pending) == 1 {
+			b.timer = time.AfterFunc(b.linger, b.flush)
+		}
+	}
+	return calls
+}
+
+// leave drops a caller from calls it no longer waits for. A call without waiters is forgotten,
+// so a later caller of the same text starts a new one, and the batch is cancelled when none of its calls has waiters
+func (b *batchingEmbedder) leave(calls []*embedCall) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, call := range calls {
+		select {
+		case <-call.done:
+			continue
+		default:
+		}
+		call.waiters--
+		if call.waiters > 0 {
+			continue
+		}
+		if b.calls[call.text] == call {
+			delete(b.calls, call.text)
+		}
+		pending := call.batch == b.batch
+		if pending {
+			// Not sent yet, nobody needs the text anymore
+			b.pending = slices.DeleteFunc(b.pending, func(p *embedCall) bool { return p == call })
+		}
+		call.batch.waiting--
+		if call.batch.waiting == 0 {
+			// Later callers start a new batch instead of joining the cancelled one
+			if pending {
+				b.takePending()
+			}
+			call.batch.cancel()
+		}
+	}
+}
+
+// takePending detaches the pending batch, callers hold mu
+func (b *batchingEmbedder) takePending() []*embedCall {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	b.batch = nil
+	return batch
+}
+
+func (b *batchingEmbedder) flush() {
+	b.mu.Lock()
+	batch := b.takePending()
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		b.send(batch)
+	}
+}
+
+// send embeds the batch and fans the vectors out to the waiting callers.
+// The batch outlives any single caller and stops when the last one leaves
+func (b *batchingEmbedder) send(calls []*embedCall) {
+	batch := calls[0].batch
+	defer batch.cancel()
+
+	texts := make([]string, len(calls))
+	for i, call := range calls {
+		texts[i] = call.text
+	}
+	vectors, errs := b.embed(batch.ctx, texts)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, call := range calls {
+		if b.calls[call.text] == call {
+			delete(b.calls, call.text)
+		}
+		call.vector, call.err = vectors[i], errs[i]
+		close(call.done)
+	}
+}
+
+// embed returns a vector or an error per text. The embedder rejects a whole request for one bad text,
+// then the texts are retried one by one so the rejection reaches only the callers of that text
+func (b *batchingEmbedder) embed(ctx context.Context, batch []string) ([][]float32, []error) {
+	errs := make([]error, len(batch))
+	vectors, err := b.IEmbedderService.EmbedBatch(ctx, batch)
+	if err == nil && len(vectors) != len(batch) {
+		err = fmt.Errorf("embedder returned %d embeddings for %d texts", len(vectors), len(batch))
+	}
+	if err == nil {
+		return vectors, errs
+	}
+
+	vectors = make([][]float32, len(batch))
+	if !errors.Is(err, ErrEmbedderBadInput) || len(batch) == 1 {
+		for i := range errs {
+			errs[i] = err
+		}
+		return vectors, errs
+	}
+	for i, text := range batch {
+		single, err := b.IEmbedderService.EmbedBatch(ctx, []string{text})
+		if err == nil && len(single) != 1 {
+			err = fmt.Errorf("embedder returned %d embeddings for 1 text", len(single))
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		vectors[i] = single[0]
+	}
+	return vectors, errs
+}
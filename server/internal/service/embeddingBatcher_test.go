@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingEmbedder struct {
+	IEmbedderService
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+	reject  string
+}
+
+func (e *recordingEmbedder) EmbedBatch(_ context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.batches = append(e.batches, texts)
+	e.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+	if slices.Contains(texts, e.reject) {
+		return nil, fmt.Errorf("%w: status 413: input is too long", ErrEmbedderBadInput)
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func newTestBatcher(next IEmbedderService, linger time.Duration, maxBatch int) *batchingEmbedder {
+	return &batchingEmbedder{IEmbedderService: next, linger: linger, maxBatch: maxBatch, calls: make(map[string]*embedCall)}
+}
+
+func TestBatchingEmbedderCoalescesConcurrentCalls(t *testing.T) {
+	next := &recordingEmbedder{}
+	batcher := newTestBatcher(next, 20*time.Millisecond, 10)
+
+	texts := []string{"go", "kafka", "go", "rust", "kafka"}
+	results := make([][]float32, len(texts))
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vector, err := batcher.Embed(context.Background(), text)
+			assert.NoError(t, err)
+			results[i] = vector
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, next.batches, 1)
+	assert.ElementsMatch(t, []string{"go", "kafka", "rust"}, next.batches[0])
+	for i, text := range texts {
+		assert.Equal(t, []float32{float32(len(text))}, results[i])
+	}
+}
+
+func TestBatchingEmbedderSendsFullBatchWithoutLinger(t *testing.T) {
+	next := &recordingEmbedder{err: errors.New("embedder is down")}
+	batcher := newTestBatcher(next, time.Hour, 2)
+
+	errs := make(chan error, 2)
+	for _, text := range []string{"a", "b"} {
+		go func() {
+			_, err := batcher.Embed(context.Background(), text)
+			errs <- err
+		}()
+	}
+
+	for range 2 {
+		select {
+		case err := <-errs:
+			assert.EqualError(t, err, "embedder is down")
+		case <-time.After(time.Second):
+			t.Fatal("full batch waited for the linger")
+		}
+	}
+	assert.Len(t, next.batches, 1)
+}
+
+func TestBatchingEmbedderStopsWaitingOnCancel(t *testing.T) {
+	batcher := newTestBatcher(&recordingEmbedder{}, time.Hour, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := batcher.Embed(ctx, "go")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBatchingEmbedderRejectsOnlyTheBadText(t *testing.T) {
+	next := &recordingEmbedder{reject: "too long"}
+	batcher := newTestBatcher(next, time.Hour, 3)
+
+	texts := []string{"go", "too long", "rust"}
+	errs := make([]error, len(texts))
+	results := make([][]float32, len(texts))
+	var wg sync.WaitGroup
+	for i, text := range texts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = batcher.Embed(context.Background(), text)
+		}()
+	}
+	wg.Wait()
+
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], ErrEmbedderBadInput)
+	assert.NoError(t, errs[2])
+	assert.Equal(t, []float32{2}, results[0])
+	assert.Equal(t, []float32{4}, results[2])
+	assert.Len(t, next.batches, 4)
+}
+
+// blockingEmbedder holds every batch until release is closed or the batch context is cancelled
+type blockingEmbedder struct {
+	IEmbedderService
+	started  chan struct{}
+	release  chan struct{}
+	canceled chan struct{}
+}
+
+func (e *blockingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	close(e.started)
+	select {
+	case <-e.release:
+		return make([][]float32, len(texts)), nil
+	case <-ctx.Done():
+		close(e.canceled)
+		return nil, ctx.Err()
+	}
+}
+
+func newBlockingEmbedder() *blockingEmbedder {
+	return &blockingEmbedder{started: make(chan struct{}), release: make(chan struct{}), canceled: make(chan struct{})}
+}
+
+func TestBatchingEmbedderCancelsBatchWhenLastCallerLeaves(t *testing.T) {
+	next := newBlockingEmbedder()
+	batcher := newTestBatcher(next, time.Millisecond, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := batcher.EmbedBatch(ctx, []string{"go", "rust"})
+		errs <- err
+	}()
+	<-next.started
+	cancel()
+
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-next.canceled:
+	case <-time.After(time.Second):
+		t.Fatal("batch kept running without callers")
+	}
+}
+
+func TestBatchingEmbedderKeepsBatchForRemainingCallers(t *testing.T) {
+	next := newBlockingEmbedder()
+	batcher := newTestBatcher(next, time.Hour, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	left := make(chan error, 1)
+	stayed := make(chan error, 1)
+	go func() {
+		_, err := batcher.Embed(ctx, "go")
+		left <- err
+	}()
+	go func() {
+		_, err := batcher.Embed(context.Background(), "go")
+		stayed <- err
+	}()
+	assert.Eventually(t, func() bool {
+		batcher.mu.Lock()
+		defer batcher.mu.Unlock()
+		call, ok := batcher.calls["go"]
+		return ok && call.waiters == 2
+	}, time.Second, time.Millisecond)
+	go batcher.flush()
+	<-next.started
+	cancel()
+	assert.ErrorIs(t, <-left, context.Canceled)
+
+	close(next.release)
+	assert.NoError(t, <-stayed)
+	select {
+	case <-next.canceled:
+		t.Fatal("batch was cancelled while a caller waited")
+	default:
+	}
+}
+
+func TestBatchingEmbedderStartsNewBatchAfterPendingOneWasCancelled(t *testing.T) {
+	next := &recordingEmbedder{}
+	batcher := newTestBatcher(next, 50*time.Millisecond, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err := batcher.Embed(ctx, "go")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	vector, err := batcher.Embed(context.Background(), "rust")
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{4}, vector)
+	assert.Equal(t, [][]string{{"rust"}}, next.batches)
+}
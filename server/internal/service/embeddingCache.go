@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/penglongli/gin-metrics/ginmetrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"math"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"strings"
+	"time"
+)
+
+const (
+	embeddingCachePrefix = "embedding:"
+	// embeddingModelKey - model the cached vectors were built with
+	embeddingModelKey = embeddingCachePrefix + "model"
+	// embeddingCacheTimeout - the cache is an optimization, a slow Redis must not slow down embedding
+	embeddingCacheTimeout = 200 * time.Millisecond
+	embeddingCacheMetric  = "embedding_cache_requests_total"
+)
+
+var embeddingCacheResults = struct {
+	Hit  string
+	Miss string
+}{
+	Hit:  "hit",
+	Miss: "miss",
+}
+
+// cachedEmbedder stores embeddings in Redis by model and text, reranking is not cached
+type cachedEmbedder struct {
+	IEmbedderService
+	rdb   *redis.Client
+	model string
+	ttl   time.Duration
+}
+
+// NewCachedEmbedder wraps next with the Redis cache. Vectors of a previous model are dropped on start
+func NewCachedEmbedder(ctx context.Context, next IEmbedderService, rdb *redis.Client, cfg config.EmbedderConfig) IEmbedderService {
+	if cfg.CacheTTL <= 0 {
+		return next
+	}
+
+	telemetry.AddMetric(&ginmetrics.Metric{
+		Type:        ginmetrics.Counter,
+		Name:        embeddingCacheMetric,
+		Description: "Embedding cache lookups by result: hit or miss",
+		Labels:      []string{"result"},
+	})
+
+	cache := &cachedEmbedder{next, rdb, next.ModelID() + "@" + cfg.ModelVersion, cfg.CacheTTL}
+	if err := cache.invalidateOnModelChange(ctx); err != nil {
+		telemetry.Log.Warn("Failed to invalidate embedding cache", zap.Error(err))
+	}
+	return cache
+}
+
+func (s *cachedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := s.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch embeds only the texts missing in the cache
+func (s *cachedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	vectors := s.lookup(ctx, texts)
+	missing := make([]string, 0)
+	missingIdx := make([]int, 0)
+	for i, vector := range vectors {
+		if vector == nil {
+			missing = append(missing, texts[i])
+			missingIdx = append(missingIdx, i)
+		}
+	}
+	countEmbeddingCache(embeddingCacheResults.Hit, len(texts)-len(missing))
+	countEmbeddingCache(embeddingCacheResults.Miss, len(missing))
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	embedded, err := s.IEmbedderService.EmbedBatch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(embedded), len(missing))
+	}
+	for i, idx := range missingIdx {
+		vectors[idx] = embedded[i]
+	}
+	s.store(ctx, missing, embedded)
+	return vectors, nil
+}
+
+// lookup returns nil for texts that are not cached, Redis errors count as misses
+func (s *cachedEmbedder) lookup(ctx context.Context, texts []string) [][]float32 {
+	vectors := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+	for i, text := range texts {
+		keys[i] = embeddingCacheKey(s.model, text)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, embeddingCacheTimeout)
+	defer cancel()
+	values, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		telemetry.Log.Warn("Failed to read embedding cache", zap.Error(err))
+		return vectors
+	}
+
+	for i, value := range values {
+		if encoded, ok := value.(string); ok {
+			vectors[i] = decodeVector([]byte(encoded))
+		}
+	}
+	return vectors
+}
+
+// store keeps the vectors even if the caller is gone by now, they were expensive to get
+func (s *cachedEmbedder) store(ctx context.Context, texts []string, vectors [][]float32) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), embeddingCacheTimeout)
+	defer cancel()
+
+	pipe := s.rdb.Pipeline()
+	for i, text := range texts {
+		pipe.Set(ctx, embeddingCacheKey(s.model, text), encodeVector(vectors[i]), s.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		telemetry.Log.Warn("Failed to write embedding cache", zap.Error(err))
+	}
+}
+
+// invalidateOnModelChange drops every cached vector when the configured model differs from the one they were built with
+func (s *cachedEmbedder) invalidateOnModelChange(ctx context.Context) error {
+	previous, err := s.rdb.Get(ctx, embeddingModelKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if previous == s.model {
+		return nil
+	}
+
+	removed, err := s.invalidate(ctx)
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		telemetry.Log.Info(fmt.Sprintf("Embedding model changed from %s to %s, dropped %d cached vectors", previous, s.model, removed))
+	}
+	return s.rdb.Set(ctx, embeddingModelKey, s.model, 0).Err()
+}
+
+// invalidate removes all cached embeddings
+func (s *cachedEmbedder) invalidate(ctx context.Context) (int, error) {
+	removed := 0
+	iter := s.rdb.Scan(ctx, 0, embeddingCachePrefix+"*", 1000).Iterator()
+	batch := make([]string, 0, 1000)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.rdb.Unlink(ctx, batch...).Err(); err != nil {
+			return err
+		}
+		removed += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for iter.Next(ctx) {
+		if iter.Val() == embeddingModelKey {
+			continue
+		}
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return removed, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return removed, err
+	}
+	return removed, flush()
+}
+
+// embeddingCacheKey - texts differing only in whitespace share the key
+func embeddingCacheKey(model string, text string) string {
+	hash := sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+	return embeddingCachePrefix + model + ":" + hex.EncodeToString(hash[:])
+}
+
+func encodeVector(vector []float32) []byte {
+	encoded := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(encoded[4*i:], math.Float32bits(v))
+	}
+	return encoded
+}
+
+// decodeVector returns nil for a corrupted value, it is treated as a miss
+func decodeVector(encoded []byte) []float32 {
+	if len(encoded) == 0 || len(encoded)%4 != 0 {
+		return nil
+	}
+	vector := make([]float32, len(encoded)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(encoded[4*i:]))
+	}
+	return vector
+}
+
+func countEmbeddingCache(result string, count int) {
+	if count > 0 {
+		_ = ginmetrics.GetMonitor().GetMetric(embeddingCacheMetric).Add([]string{result}, float64(count))
+	}
+}
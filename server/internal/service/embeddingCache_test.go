@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"semki/pkg/telemetry"
+	"testing"
+	"time"
+)
+
+type countingEmbedder struct {
+	IEmbedderService
+	texts []string
+}
+
+func (e *countingEmbedder) EmbedBatch(_ context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingCacheKey(t *testing.T) {
+	assert.Equal(t, embeddingCacheKey("m1", "kubernetes  operator"), embeddingCacheKey("m1", " kubernetes operator\n"))
+	assert.NotEqual(t, embeddingCacheKey("m1", "kubernetes"), embeddingCacheKey("m2", "kubernetes"))
+	assert.NotEqual(t, embeddingCacheKey("m1", "kubernetes"), embeddingCacheKey("m1", "kafka"))
+}
+
+func TestVectorEncoding(t *testing.T) {
+	vector := []float32{0.25, -1.5, 3e-7}
+	assert.Equal(t, vector, decodeVector(encodeVector(vector)))
+	assert.Nil(t, decodeVector([]byte{1, 2, 3}))
+}
+
+func TestEmbeddingCacheFallsBackWithoutRedis(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	next := &countingEmbedder{}
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	defer rdb.Close()
+	cache := &cachedEmbedder{next, rdb, "m1", time.Hour}
+
+	vectors, err := cache.EmbedBatch(context.Background(), []string{"go", "kafka"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{2}, {5}}, vectors)
+	assert.Equal(t, []string{"go", "kafka"}, next.texts)
+}
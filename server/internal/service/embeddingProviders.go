@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"time"
+)
+
+// embeddingProbeTimeout - the probe only learns dimensions, a slow embedder must not block the start
+const embeddingProbeTimeout = 10 * time.Second
+
+// errRerankUnsupported - the provider has no cross-encoder, search keeps the vector order
+var errRerankUnsupported = errors.New("embedding provider does not support reranking")
+
+//region EmbeddingProvider
+
+type EmbeddingProvider string
+
+var EmbeddingProviders = struct {
+	HTTP    EmbeddingProvider
+	OpenAI  EmbeddingProvider
+	Hashing EmbeddingProvider
+}{
+	HTTP:    "http",
+	OpenAI:  "openai",
+	Hashing: "hashing",
+}
+
+//endregion
+
+// NewEmbeddingProvider creates the provider selected in cfg. Providers that learn their dimensions
+// from responses embed a probe text, if the embedder is down the dimensions stay unknown
+func NewEmbeddingProvider(ctx context.Context, cfg config.EmbedderConfig) (IEmbedderService, error) {
+	var provider IEmbedderService
+	switch EmbeddingProvider(cfg.Provider) {
+	case EmbeddingProviders.HTTP:
+		provider = NewEmbedderService(cfg)
+	case EmbeddingProviders.OpenAI:
+		provider = NewOpenAIEmbedder(cfg)
+	case EmbeddingProviders.Hashing:
+		return NewHashingEmbedder(cfg.Dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+	}
+
+	if provider.Dimensions() == 0 {
+		probeCtx, cancel := context.WithTimeout(ctx, embeddingProbeTimeout)
+		defer cancel()
+		if _, err := provider.Embed(probeCtx, "dimension probe"); err != nil {
+			telemetry.Log.Warn(fmt.Sprintf("Failed to probe dimensions of embedding model %s: %s", provider.ModelID(), err))
+		}
+	}
+	return provider, nil
+}
+
+// embedBatchWithIDs - for providers without an endpoint of their own
+func embedBatchWithIDs(ctx context.Context, embedder IEmbedderService, texts []TextWithID) ([]EmbeddingWithID, error) {
+	plain := make([]string, len(texts))
+	for i, text := range texts {
+		plain[i] = text.Text
+	}
+
+	vectors, err := embedder.EmbedBatch(ctx, plain)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(vectors), len(texts))
+	}
+
+	result := make([]EmbeddingWithID, len(texts))
+	for i, text := range texts {
+		result[i] = EmbeddingWithID{ID: text.ID, Embedding: vectors[i]}
+	}
+	return result, nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"semki/internal/adapter/qdrant"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"sort"
+)
+
+// resolveFacets names facet ids from the organization. Ids of deleted teams, levels and locations are dropped
+func resolveFacets(counts qdrant.FacetCounts, semantic model.OrganizationSemantic) dto.SearchFacets {
+	teams, levels, locations := organizationFilterValues(semantic)
+	return dto.SearchFacets{
+		Teams:     facetCounts(counts.Teams, teams),
+		Levels:    facetCounts(counts.Levels, levels),
+		Locations: facetCounts(counts.Locations, locations),
+	}
+}
+
+// facetCounts - most common first, names break ties for a stable order
+func facetCounts(counts map[string]int, values []dto.SearchFilterValue) []dto.FacetCount {
+	result := make([]dto.FacetCount, 0, len(counts))
+	for _, v := range values {
+		if count := counts[v.ID]; count > 0 {
+			result = append(result, dto.FacetCount{ID: v.ID, Name: v.Name, Count: count})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
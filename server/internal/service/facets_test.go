@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"semki/internal/utils/config"
+	"testing"
+)
+
+// facetRepo records the filters facets are counted with
+type facetRepo struct {
+	qdrant.IQdrantRepository
+	filters qdrant.SearchFilters
+	vector  []float32
+}
+
+func (r *facetRepo) CountFacets(_ context.Context, vector []float32, filters qdrant.SearchFilters, _ float32) (qdrant.FacetCounts, error) {
+	r.filters, r.vector = filters, vector
+	return qdrant.FacetCounts{}, nil
+}
+
+func TestCountFacetsIgnoresSelectedFilters(t *testing.T) {
+	repo := &facetRepo{}
+	// No embedder, the vector of the search is reused
+	s := &qdrantService{repo: repo, searchCfg: config.SearchConfig{FacetCandidates: 200}}
+
+	_, err := s.CountFacets(context.Background(), qdrant.SearchFilters{
+		OrganizationID: "org",
+		Query:          "golang",
+		Mode:           qdrant.SearchModes.Semantic,
+		Teams:          []string{"team"},
+		ExcludeLevels:  []string{"level"},
+		ExcludeUserIDs: []string{"shown"},
+		Limit:          5,
+	}, []float32{1, 0})
+	assert.NoError(t, err)
+
+	assert.Equal(t, qdrant.SearchFilters{OrganizationID: "org", Query: "golang", Mode: qdrant.SearchModes.Semantic, Limit: 200}, repo.filters)
+	assert.Equal(t, []float32{1, 0}, repo.vector)
+}
+
+func TestResolveFacets(t *testing.T) {
+	engineering := model.Team{ID: primitive.NewObjectID(), Name: "Engineering"}
+	design := model.Team{ID: primitive.NewObjectID(), Name: "Design"}
+	semantic := model.OrganizationSemantic{Teams: []model.Team{design, engineering}}
+
+	facets := resolveFacets(qdrant.FacetCounts{
+		Teams: map[string]int{
+			engineering.ID.Hex():          12,
+			design.ID.Hex():               3,
+			primitive.NewObjectID().Hex(): 5,
+		},
+	}, semantic)
+
+	assert.Equal(t, []dto.FacetCount{
+		{ID: engineering.ID.Hex(), Name: "Engineering", Count: 12},
+		{ID: design.ID.Hex(), Name: "Design", Count: 3},
+	}, facets.Teams)
+	assert.Empty(t, facets.Levels)
+}
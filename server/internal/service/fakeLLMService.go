@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"semki/internal/model"
+	"semki/pkg/lib"
+	"slices"
+	"strings"
+)
+
+// fakeStreamChunk - bytes of the assessment JSON per delta
+const fakeStreamChunk = 16
+
+// fakeLLMService answers from word overlap of the query and the user description. It needs no model
+// or network and gives the same answer for the same input, so offline runs and tests are reproducible
+type fakeLLMService struct{}
+
+func NewFakeLLMService() ILLMService {
+	return &fakeLLMService{}
+}
+
+// DescribeUser lists query terms found in the description as strengths and the others as gaps
+func (s *fakeLLMService) DescribeUser(_ context.Context, query string, _ model.Organization, user model.User) (model.MatchAssessment, error) {
+	terms := uniqueTerms(query)
+	matched := matchedTerms(query, user.Semantic.Description)
+
+	assessment := model.MatchAssessment{
+		Strengths:      make([]string, 0, len(matched)),
+		Gaps:           make([]string, 0, len(terms)-len(matched)),
+		ContactChannel: assessmentChannels(user)[0],
+	}
+	for _, term := range terms {
+		if slices.Contains(matched, term) {
+			assessment.Strengths = append(assessment.Strengths, "Mentions "+term)
+		} else {
+			assessment.Gaps = append(assessment.Gaps, "No mention of "+term)
+		}
+	}
+	if len(terms) > 0 {
+		assessment.FitScore = float32(len(matched)) / float32(len(terms))
+	}
+	assessment.OpeningQuestion = fmt.Sprintf("What is your experience with %s?", strings.Join(terms, ", "))
+	return assessment, nil
+}
+
+// StreamDescribeUser sends the JSON of the DescribeUser assessment in small chunks
+func (s *fakeLLMService) StreamDescribeUser(ctx context.Context, query string, org model.Organization, user model.User, onDelta func(attempt int, delta string)) (model.MatchAssessment, error) {
+	assessment, _ := s.DescribeUser(ctx, query, org, user)
+	content, err := json.Marshal(assessment)
+	if err != nil {
+		return model.MatchAssessment{}, err
+	}
+	for chunk := range slices.Chunk(content, fakeStreamChunk) {
+		if err := ctx.Err(); err != nil {
+			return model.MatchAssessment{}, err
+		}
+		onDelta(1, string(chunk))
+	}
+	return assessment, nil
+}
+
+// RerankUsers scores every user by the share of query terms found in their description
+func (s *fakeLLMService) RerankUsers(_ context.Context, query string, _ model.Organization, users []model.User) ([]float32, error) {
+	terms := len(uniqueTerms(query))
+	scores := make([]float32, len(users))
+	if terms == 0 {
+		return scores, nil
+	}
+	for i, user := range users {
+		scores[i] = float32(len(matchedTerms(query, user.Semantic.Description))) / float32(terms)
+	}
+	return scores, nil
+}
+
+// RewriteQuery searches the follow-up as is, filters are left to the user
+func (s *fakeLLMService) RewriteQuery(_ context.Context, _ model.Organization, _ string, message string) (QueryRewrite, error) {
+	return QueryRewrite{Query: message}, nil
+}
+
+// matchedTerms - distinct query terms found in the text, in query order
+func matchedTerms(query string, text string) []string {
+	words := make(map[string]struct{})
+	for _, token := range lib.Tokenize(text) {
+		words[token] = struct{}{}
+	}
+
+	matched := make([]string, 0)
+	for _, term := range uniqueTerms(query) {
+		if _, ok := words[term]; ok {
+			matched = append(matched, term)
+		}
+	}
+	return matched
+}
+
+func uniqueTerms(text string) []string {
+	seen := make(map[string]struct{})
+	terms := make([]string, 0)
+	for _, token := range lib.Tokenize(text) {
+		if _, ok := seen[token]; !ok {
+			seen[token] = struct{}{}
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
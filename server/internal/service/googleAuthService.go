@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	jwt "github.com/appleboy/gin-jwt/v3"
@@ -18,16 +19,16 @@ import (
 
 // authService - dependent services
 type googleAuthService struct {
-	qdrantService IQdrantService
-	userRepo      mongo.IUserRepository
-	orgRepo       mongo.IOrganizationRepository
-	google        google.Google
-	jwtAuth       *jwt.GinJWTMiddleware
-	frontendUrl   string
+	outbox      IOutboxService
+	userRepo    mongo.IUserRepository
+	orgRepo     mongo.IOrganizationRepository
+	google      google.Google
+	jwtAuth     *jwt.GinJWTMiddleware
+	frontendUrl string
 }
 
 func NewGoogleAuthService(
-	qdrantService IQdrantService,
+	outbox IOutboxService,
 	userRepo mongo.IUserRepository,
 	orgRepo mongo.IOrganizationRepository,
 	google google.Google,
@@ -35,7 +36,7 @@ func NewGoogleAuthService(
 	frontendUrl string,
 ) routes.IGoogleAuthService {
 
-	return &googleAuthService{qdrantService, userRepo, orgRepo, google, jwtAuth, frontendUrl}
+	return &googleAuthService{outbox, userRepo, orgRepo, google, jwtAuth, frontendUrl}
 }
 
 // GoogleLoginHandler godoc
@@ -111,7 +112,10 @@ func (s *googleAuthService) GoogleAuthCallback(c *gin.Context) {
 			// Invited users has its Role. Probably fix it later
 			userFromDB.OrganizationRole = model.OrganizationRoles.OWNER
 		}
-		if err := s.userRepo.CreateUser(ctx, userFromDB); err != nil {
+		err = s.outbox.Write(ctx, userFromDB.ID, func(ctx context.Context) error {
+			return s.userRepo.CreateUser(ctx, userFromDB)
+		})
+		if err != nil {
 			c.Redirect(http.StatusFound, s.frontendUrl+"/login?error=internal%20error%20create-user")
 			return
 		}
@@ -121,16 +125,15 @@ func (s *googleAuthService) GoogleAuthCallback(c *gin.Context) {
 			return
 		}
 		userFromDB.Providers = append(userFromDB.Providers, model.UserProviders.Google)
-		if err := s.userRepo.UpdateUser(ctx, userFromDB.ID, *userFromDB); err != nil {
+		err = s.outbox.Write(ctx, userFromDB.ID, func(ctx context.Context) error {
+			return s.userRepo.UpdateUser(ctx, userFromDB.ID, *userFromDB)
+		})
+		if err != nil {
 			c.Redirect(http.StatusFound, s.frontendUrl+"/login?error=internal%20error%20update%20provider")
 			return
 		}
 	}
 
-	if err := s.qdrantService.IndexUser(ctx, userFromDB); err != nil {
-		telemetry.Log.Error("Failed to index created user from Google in Qdrant: " + err.Error())
-	}
-
 	// Token
 	jwtToken, err := s.jwtAuth.TokenGenerator(userFromDB)
 	if err != nil {
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"semki/pkg/lib"
+)
+
+// hashingEmbedder hashes words into a fixed number of buckets. It needs no model or network
+// and returns the same vector for the same text, so offline tests and CI get stable rankings
+type hashingEmbedder struct {
+	dimensions int
+}
+
+func NewHashingEmbedder(dimensions int) IEmbedderService {
+	return &hashingEmbedder{dimensions: dimensions}
+}
+
+func (s *hashingEmbedder) ModelID() string {
+	return fmt.Sprintf("hashing-%d", s.dimensions)
+}
+
+func (s *hashingEmbedder) Dimensions() int {
+	return s.dimensions
+}
+
+// Embed adds ±1 per word to its bucket, the sign halves collisions of unrelated words. The vector is unit length
+func (s *hashingEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	if s.dimensions <= 0 {
+		return nil, fmt.Errorf("%w: hashing embedder needs positive dimensions", ErrEmbedderBadInput)
+	}
+
+	vector := make([]float32, s.dimensions)
+	for _, token := range lib.Tokenize(text) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(token))
+		sum := h.Sum64()
+		if sum>>63 == 1 {
+			vector[sum%uint64(s.dimensions)]--
+		} else {
+			vector[sum%uint64(s.dimensions)]++
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v * v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector, nil
+}
+
+func (s *hashingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, err := s.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func (s *hashingEmbedder) EmbedBatchWithIDs(ctx context.Context, texts []TextWithID) ([]EmbeddingWithID, error) {
+	return embedBatchWithIDs(ctx, s, texts)
+}
+
+// Rerank scores texts by cosine similarity to the query
+func (s *hashingEmbedder) Rerank(ctx context.Context, query string, texts []string) ([]float32, error) {
+	queryVector, err := s.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := s.EmbedBatch(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float32, len(texts))
+	for i, vector := range vectors {
+		for j := range vector {
+			scores[i] += vector[j] * queryVector[j]
+		}
+	}
+	return scores, nil
+}
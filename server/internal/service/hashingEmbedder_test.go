@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestHashingEmbedderIsDeterministic(t *testing.T) {
+	embedder := NewHashingEmbedder(64)
+
+	first, err := embedder.Embed(context.Background(), "Go backend, Kafka")
+	assert.NoError(t, err)
+	second, err := embedder.Embed(context.Background(), "go BACKEND kafka")
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Len(t, first, 64)
+
+	var norm float64
+	for _, v := range first {
+		norm += float64(v * v)
+	}
+	assert.InDelta(t, 1, math.Sqrt(norm), 1e-6)
+	assert.Equal(t, "hashing-64", embedder.ModelID())
+}
+
+func TestHashingEmbedderRerank(t *testing.T) {
+	embedder := NewHashingEmbedder(256)
+
+	scores, err := embedder.Rerank(context.Background(), "kafka streaming", []string{"frontend react", "kafka streaming platform"})
+	assert.NoError(t, err)
+	assert.Greater(t, scores[1], scores[0])
+}
+
+func TestNewEmbeddingProviderRejectsUnknown(t *testing.T) {
+	_, err := NewEmbeddingProvider(context.Background(), configWithProvider("word2vec"))
+	assert.Error(t, err)
+
+	provider, err := NewEmbeddingProvider(context.Background(), configWithProvider("hashing"))
+	assert.NoError(t, err)
+	assert.Equal(t, 32, provider.Dimensions())
+}
@@ -2,52 +2,240 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/sashabaranov/go-openai"
+	"io"
 	"semki/internal/model"
+	"semki/internal/utils/config"
+	"slices"
+	"strings"
+	"time"
 )
 
+//region LLMProvider
+
+type LLMProvider string
+
+var LLMProviders = struct {
+	OpenAI     LLMProvider
+	Compatible LLMProvider
+	Fake       LLMProvider
+}{
+	OpenAI:     "openai",
+	Compatible: "compatible",
+	Fake:       "fake",
+}
+
+//endregion
+
 type ILLMService interface {
-	DescribeUser(ctx context.Context, query string, org model.Organization, user model.User) (string, error)
+	// DescribeUser assesses how the user fits the query
+	DescribeUser(ctx context.Context, query string, org model.Organization, user model.User) (model.MatchAssessment, error)
+	// StreamDescribeUser passes every chunk of the assessment JSON to onDelta as it is generated, chunks of a
+	// retried attempt replace the earlier ones
+	StreamDescribeUser(ctx context.Context, query string, org model.Organization, user model.User, onDelta func(attempt int, delta string)) (model.MatchAssessment, error)
+	RerankUsers(ctx context.Context, query string, org model.Organization, users []model.User) ([]float32, error)
+	RewriteQuery(ctx context.Context, org model.Organization, history string, message string) (QueryRewrite, error)
 }
 
+// QueryRewrite - standalone query with team, level and location names of the organization
+type QueryRewrite struct {
+	Query     string   `json:"query"`
+	Teams     []string `json:"teams"`
+	Levels    []string `json:"levels"`
+	Locations []string `json:"locations"`
+}
+
+// LLMService talks to OpenAI or any server speaking its chat completions API
 type LLMService struct {
-	client *openai.Client
+	client        *openai.Client
+	model         string
+	allowedModels []string
+	timeout       time.Duration
 }
 
-func NewLLMService(openAIKey string) ILLMService {
-	client := openai.NewClient(openAIKey)
-	return &LLMService{client: client}
+// NewLLMService creates the backend selected in cfg
+func NewLLMService(cfg config.LLMConfig) (ILLMService, error) {
+	var clientConfig openai.ClientConfig
+	switch LLMProvider(cfg.Provider) {
+	case LLMProviders.OpenAI:
+		if cfg.APIKey == "" {
+			return nil, errors.New("LLM_API_KEY is required for the openai provider, use the fake provider to run without one")
+		}
+		clientConfig = openai.DefaultConfig(cfg.APIKey)
+	case LLMProviders.Compatible:
+		if cfg.BaseURL == "" {
+			return nil, errors.New("LLM_BASE_URL is required for the compatible provider")
+		}
+		// Local servers usually accept any key
+		clientConfig = openai.DefaultConfig(cfg.APIKey)
+		clientConfig.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	case LLMProviders.Fake:
+		return NewFakeLLMService(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+
+	if cfg.Model == "" {
+		return nil, errors.New("LLM_MODEL is required")
+	}
+	return &LLMService{
+		client:        openai.NewClientWithConfig(clientConfig),
+		model:         cfg.Model,
+		allowedModels: cfg.AllowedModels,
+		timeout:       cfg.Timeout,
+	}, nil
 }
 
-func (s *LLMService) DescribeUser(ctx context.Context, query string, org model.Organization, user model.User) (string, error) {
-	teamName := ""
-	levelName := ""
-	locationName := user.Semantic.Location.Hex()
+// ValidateLLMModel checks a model an organization wants to use, an empty model means the default one
+func ValidateLLMModel(cfg config.LLMConfig, model string) error {
+	if model == "" || len(cfg.AllowedModels) == 0 || slices.Contains(cfg.AllowedModels, model) {
+		return nil
+	}
+	return fmt.Errorf("model %q is not allowed, choose one of %s", model, strings.Join(cfg.AllowedModels, ", "))
+}
 
-	for _, t := range org.Semantic.Teams {
-		if t.ID == user.Semantic.Team {
-			teamName = t.Name
-			break
-		}
+// modelFor returns the model chosen by the organization, models removed from the allowed list fall back to the default
+func (s *LLMService) modelFor(org model.Organization) string {
+	if org.LLMModel == "" || (len(s.allowedModels) > 0 && !slices.Contains(s.allowedModels, org.LLMModel)) {
+		return s.model
 	}
+	return org.LLMModel
+}
 
-	for _, l := range org.Semantic.Levels {
-		if l.ID == user.Semantic.Level {
-			levelName = l.Name
+func (s *LLMService) request(org model.Organization, system string, prompt string) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model: s.modelFor(org),
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: system},
+			{Role: openai.ChatMessageRoleUser, Content: prompt},
+		},
+	}
+}
+
+// withTimeout bounds a whole completion, streamed ones included
+func (s *LLMService) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(ctx, s.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// complete runs a single chat completion with the model of the organization
+func (s *LLMService) complete(ctx context.Context, org model.Organization, system string, prompt string, jsonOnly bool) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	request := s.request(org, system, prompt)
+	if jsonOnly {
+		request.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return s.send(ctx, request)
+}
+
+func (s *LLMService) send(ctx context.Context, request openai.ChatCompletionRequest) (string, error) {
+	resp, err := s.client.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from model")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// sendStream runs a streamed chat completion, onDelta gets the content of every chunk
+func (s *LLMService) sendStream(ctx context.Context, request openai.ChatCompletionRequest, onDelta func(string)) (string, error) {
+	stream, err := s.client.CreateChatCompletionStream(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
 			break
 		}
+		if err != nil {
+			return content.String(), err
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		onDelta(chunk.Choices[0].Delta.Content)
 	}
+	return content.String(), nil
+}
 
-	for _, loc := range org.Semantic.Locations {
-		if loc.ID == user.Semantic.Location {
-			locationName = loc.Name
-			break
+const describeUserSystemPrompt = "You are a helpful AI assistant assessing how colleagues fit a search. You only answer with JSON."
+
+func (s *LLMService) DescribeUser(ctx context.Context, query string, org model.Organization, user model.User) (model.MatchAssessment, error) {
+	return s.assessUser(ctx, query, org, user, nil)
+}
+
+func (s *LLMService) StreamDescribeUser(ctx context.Context, query string, org model.Organization, user model.User, onDelta func(attempt int, delta string)) (model.MatchAssessment, error) {
+	return s.assessUser(ctx, query, org, user, onDelta)
+}
+
+// assessUser asks for an assessment constrained by a JSON schema. Malformed output is asked for again
+// together with what was wrong with it, the timeout covers all attempts
+func (s *LLMService) assessUser(ctx context.Context, query string, org model.Organization, user model.User, onDelta func(attempt int, delta string)) (model.MatchAssessment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	channels := assessmentChannels(user)
+	request := s.request(org, describeUserSystemPrompt, describeUserPrompt(query, org, user, channels))
+	request.ResponseFormat = &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "match_assessment",
+			Schema: assessmentSchema(channels),
+			Strict: true,
+		},
+	}
+
+	var invalid error
+	for attempt := 1; attempt <= maxAssessmentAttempts; attempt++ {
+		var content string
+		var err error
+		if onDelta == nil {
+			content, err = s.send(ctx, request)
+		} else {
+			content, err = s.sendStream(ctx, request, func(delta string) { onDelta(attempt, delta) })
 		}
+		if err != nil {
+			return model.MatchAssessment{}, err
+		}
+
+		assessment, err := parseAssessment(content, channels)
+		if err == nil {
+			return assessment, nil
+		}
+		invalid = err
+		request.Messages = append(request.Messages,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: fmt.Sprintf("The assessment is invalid: %s. Answer again with JSON matching the schema.", err)},
+		)
 	}
+	return model.MatchAssessment{}, fmt.Errorf("no valid assessment after %d attempts: %w", maxAssessmentAttempts, invalid)
+}
 
-	prompt := fmt.Sprintf(`You are an expert recruiter analyzing candidates in the organization "%s".
-Given the following user profile and a search query, explain why this user might or might not fit the query.
+func describeUserPrompt(query string, org model.Organization, user model.User, channels []model.ContactChannel) string {
+	teamName, levelName, locationName := semanticNames(org, user)
+	names := make([]string, len(channels))
+	for i, channel := range channels {
+		names[i] = string(channel)
+	}
+
+	return fmt.Sprintf(`You are an expert recruiter analyzing candidates in the organization "%s".
+Given the following user profile and a search query, assess how well this user fits the query.
 
 Query:
 %s
@@ -58,26 +246,128 @@ Description: %s
 Team: %s
 Level: %s
 Location: %s
+Contact channels: %s
 
-Provide a concise, analytical explanation in natural English. Focus on reasoning, not summary.`, org.Title, query, user.Name, user.Semantic.Description, teamName, levelName, locationName)
+Respond with JSON only: {"fitScore": <0..1>, "strengths": [...], "gaps": [...], "openingQuestion": "...", "contactChannel": "<one of the contact channels>"}.
+Strengths and gaps are short analytical phrases, focus on reasoning, not summary.`,
+		org.Title, query, user.Name, user.Semantic.Description, teamName, levelName, locationName, strings.Join(names, ", "))
+}
 
-	resp, err := s.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: openai.GPT5Mini,
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: "You are a helpful AI assistant providing analytical reasoning about user fit."},
-				{Role: openai.ChatMessageRoleUser, Content: prompt},
-			},
-		},
-	)
+// RerankUsers scores all candidates against the query in a single completion, 0 - irrelevant, 1 - perfect fit
+func (s *LLMService) RerankUsers(ctx context.Context, query string, org model.Organization, users []model.User) ([]float32, error) {
+	var candidates strings.Builder
+	for i, user := range users {
+		teamName, levelName, locationName := semanticNames(org, user)
+		fmt.Fprintf(&candidates, "[%d] Team: %s; Level: %s; Location: %s; Description: %s\n", i, teamName, levelName, locationName, user.Semantic.Description)
+	}
+
+	prompt := fmt.Sprintf(`You are ranking colleagues in the organization "%s" for the search query below.
+Score every candidate from 0 (irrelevant) to 1 (perfect person to ask).
+
+Query:
+%s
+
+Candidates:
+%s
+Respond with JSON only: {"scores": [{"id": <candidate number>, "score": <0..1>}, ...]} with one entry per candidate.`, org.Title, query, candidates.String())
+
+	content, err := s.complete(ctx, org, "You are a precise relevance ranker. You only answer with JSON.", prompt, true)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from model")
+	return parseRerankScores(content, len(users))
+}
+
+// RewriteQuery turns a follow-up like "same but in London" into a standalone query with the full set of filters
+func (s *LLMService) RewriteQuery(ctx context.Context, org model.Organization, history string, message string) (QueryRewrite, error) {
+	teams := make([]string, 0, len(org.Semantic.Teams))
+	for _, t := range org.Semantic.Teams {
+		teams = append(teams, t.Name)
+	}
+	levels := make([]string, 0, len(org.Semantic.Levels))
+	for _, l := range org.Semantic.Levels {
+		levels = append(levels, l.Name)
+	}
+	locations := make([]string, 0, len(org.Semantic.Locations))
+	for _, loc := range org.Semantic.Locations {
+		locations = append(locations, loc.Name)
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	prompt := fmt.Sprintf(`You help to search colleagues in the organization "%s".
+Rewrite the follow-up message into a standalone search query, using the conversation so far.
+Relative requests like "someone more senior" or "same but in London" refer to the previous query, filters and suggested people.
+
+Teams: %s
+Levels (in the order they are defined): %s
+Locations: %s
+
+Conversation:
+%s
+Follow-up:
+%s
+
+Respond with JSON only: {"query": "<standalone query without filter names>", "teams": [...], "levels": [...], "locations": [...]}.
+Filters are the complete set for the new search and use only the names listed above.`,
+		org.Title, strings.Join(teams, ", "), strings.Join(levels, ", "), strings.Join(locations, ", "), history, message)
+
+	content, err := s.complete(ctx, org, "You rewrite search conversations into standalone queries. You only answer with JSON.", prompt, true)
+	if err != nil {
+		return QueryRewrite{}, err
+	}
+
+	var rewrite QueryRewrite
+	if err := json.Unmarshal([]byte(content), &rewrite); err != nil {
+		return QueryRewrite{}, fmt.Errorf("failed to parse rewritten query: %w", err)
+	}
+	return rewrite, nil
+}
+
+// parseRerankScores maps {"scores":[{"id":0,"score":0.9}]} to candidate order. Candidates the model skipped get 0
+func parseRerankScores(content string, count int) ([]float32, error) {
+	var parsed struct {
+		Scores []struct {
+			ID    int     `json:"id"`
+			Score float32 `json:"score"`
+		} `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse rerank scores: %w", err)
+	}
+
+	scores := make([]float32, count)
+	for _, s := range parsed.Scores {
+		if s.ID >= 0 && s.ID < count {
+			scores[s.ID] = s.Score
+		}
+	}
+	return scores, nil
+}
+
+// semanticNames resolves team, level and location ids of the user to organization names
+func semanticNames(org model.Organization, user model.User) (teamName, levelName, locationName string) {
+	locationName = user.Semantic.Location.Hex()
+
+	for _, t := range org.Semantic.Teams {
+		if t.ID == user.Semantic.Team {
+			teamName = t.Name
+			break
+		}
+	}
+
+	for _, l := range org.Semantic.Levels {
+		if l.ID == user.Semantic.Level {
+			levelName = l.Name
+			break
+		}
+	}
+
+	for _, loc := range org.Semantic.Locations {
+		if loc.ID == user.Semantic.Location {
+			locationName = loc.Name
+			break
+		}
+	}
+
+	return teamName, levelName, locationName
 }
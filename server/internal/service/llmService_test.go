@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"semki/internal/model"
+	"semki/internal/utils/config"
+	"testing"
+	"time"
+)
+
+func TestNewLLMServiceProviders(t *testing.T) {
+	_, err := NewLLMService(config.LLMConfig{Provider: "openai", Model: "gpt-5-mini"})
+	assert.Error(t, err, "openai needs a key")
+
+	_, err = NewLLMService(config.LLMConfig{Provider: "compatible", Model: "llama3"})
+	assert.Error(t, err, "compatible needs a base url")
+
+	_, err = NewLLMService(config.LLMConfig{Provider: "anthropic"})
+	assert.Error(t, err)
+
+	llm, err := NewLLMService(config.LLMConfig{Provider: "fake"})
+	assert.NoError(t, err)
+	assert.IsType(t, &fakeLLMService{}, llm)
+}
+
+const validAssessment = `{"fitScore": 0.8, "strengths": ["Writes Go"], "gaps": [], "openingQuestion": "Which Go services do you own?", "contactChannel": "email"}`
+
+// chatCompletion - non-streamed completion with content as the answer
+func chatCompletion(content string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": content}}},
+	})
+	return body
+}
+
+func TestCompatibleLLMUsesOrganizationModel(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		var request struct {
+			Model string `json:"model"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		models = append(models, request.Model)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(chatCompletion(validAssessment))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLMService(config.LLMConfig{
+		Provider:      "compatible",
+		BaseURL:       server.URL + "/v1/",
+		Model:         "llama3",
+		AllowedModels: []string{"llama3", "qwen2.5"},
+		Timeout:       time.Second,
+	})
+	assert.NoError(t, err)
+
+	for _, org := range []model.Organization{{}, {LLMModel: "qwen2.5"}, {LLMModel: "removed-model"}} {
+		assessment, err := llm.DescribeUser(context.Background(), "go", org, model.User{})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(0.8), assessment.FitScore)
+	}
+	assert.Equal(t, []string{"llama3", "qwen2.5", "llama3"}, models, "models outside the allowed list fall back to the default")
+}
+
+func TestStreamDescribeUserRetriesMalformedAssessment(t *testing.T) {
+	var requests []openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatCompletionRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+
+		content := `{"fitScore": 3, "strengths": ["Go"], "gaps": [], "openingQuestion": "Hi?", "contactChannel": "email"}`
+		if len(requests) > 1 {
+			content = validAssessment
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{content[:10], "", content[10:]} {
+			data, _ := json.Marshal(map[string]any{"choices": []map[string]any{{"index": 0, "delta": map[string]string{"content": chunk}}}})
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLMService(config.LLMConfig{Provider: "compatible", BaseURL: server.URL + "/v1", Model: "llama3"})
+	assert.NoError(t, err)
+
+	deltas := map[int]string{}
+	assessment, err := llm.StreamDescribeUser(context.Background(), "go", model.Organization{}, model.User{}, func(attempt int, delta string) {
+		assert.NotEmpty(t, delta, "empty chunks are skipped")
+		deltas[attempt] += delta
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Writes Go"}, assessment.Strengths)
+	assert.Equal(t, model.ContactChannels.Email, assessment.ContactChannel)
+	assert.Equal(t, validAssessment, deltas[2])
+
+	assert.Len(t, requests, 2)
+	assert.Equal(t, openai.ChatCompletionResponseFormatTypeJSONSchema, requests[0].ResponseFormat.Type)
+	retry := requests[1].Messages
+	assert.Len(t, retry, 4, "the retry shows the malformed answer and what was wrong")
+	assert.Contains(t, retry[3].Content, "fitScore 3 is outside of 0..1")
+}
+
+func TestParseAssessment(t *testing.T) {
+	channels := []model.ContactChannel{model.ContactChannels.Slack, model.ContactChannels.Email}
+
+	assessment, err := parseAssessment(`{"fitScore": 0.5, "strengths": [" Go ", ""], "gaps": ["No Rust"], "openingQuestion": "Hi?", "contactChannel": "slack"}`, channels)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go"}, assessment.Strengths, "phrases are trimmed")
+
+	for name, content := range map[string]string{
+		"not json":        `Strong fit`,
+		"missing field":   `{"fitScore": 0.5, "strengths": ["Go"], "gaps": [], "contactChannel": "slack"}`,
+		"unknown channel": `{"fitScore": 0.5, "strengths": ["Go"], "gaps": [], "openingQuestion": "Hi?", "contactChannel": "telegram"}`,
+		"score range":     `{"fitScore": -1, "strengths": ["Go"], "gaps": [], "openingQuestion": "Hi?", "contactChannel": "slack"}`,
+		"no reasons":      `{"fitScore": 0.5, "strengths": [], "gaps": [" "], "openingQuestion": "Hi?", "contactChannel": "slack"}`,
+		"no question":     `{"fitScore": 0.5, "strengths": ["Go"], "gaps": [], "openingQuestion": "", "contactChannel": "slack"}`,
+	} {
+		_, err := parseAssessment(content, channels)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestValidateLLMModel(t *testing.T) {
+	cfg := config.LLMConfig{AllowedModels: []string{"gpt-5-mini", "gpt-5"}}
+	assert.NoError(t, ValidateLLMModel(cfg, "gpt-5"))
+	assert.NoError(t, ValidateLLMModel(cfg, ""), "empty resets to the default")
+	assert.Error(t, ValidateLLMModel(cfg, "gpt-4o"))
+	assert.NoError(t, ValidateLLMModel(config.LLMConfig{}, "anything"), "no list allows any model")
+}
+
+func TestFakeLLMIsDeterministic(t *testing.T) {
+	llm := NewFakeLLMService()
+	users := []model.User{
+		{Name: "Ann", Semantic: model.UserSemantic{Description: "Go developer working on Kubernetes operators"}},
+		{Name: "Bob", Semantic: model.UserSemantic{Description: "Designer of mobile interfaces"}},
+	}
+
+	scores, err := llm.RerankUsers(context.Background(), "go kubernetes", model.Organization{}, users)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{1, 0}, scores)
+
+	first, err := llm.DescribeUser(context.Background(), "kubernetes rust", model.Organization{}, users[0])
+	assert.NoError(t, err)
+	second, _ := llm.DescribeUser(context.Background(), "kubernetes rust", model.Organization{}, users[0])
+	assert.Equal(t, first, second)
+	assert.Equal(t, []string{"Mentions kubernetes"}, first.Strengths)
+	assert.Equal(t, []string{"No mention of rust"}, first.Gaps)
+	assert.Equal(t, float32(0.5), first.FitScore)
+
+	var streamed string
+	assessment, err := llm.StreamDescribeUser(context.Background(), "kubernetes rust", model.Organization{}, users[0], func(_ int, delta string) {
+		streamed += delta
+	})
+	assert.NoError(t, err)
+	parsed, err := parseAssessment(streamed, assessmentChannels(users[0]))
+	assert.NoError(t, err, "streamed JSON passes validation")
+	assert.Equal(t, assessment, parsed)
+
+	rewrite, err := llm.RewriteQuery(context.Background(), model.Organization{}, "", "same but in London")
+	assert.NoError(t, err)
+	assert.Equal(t, "same but in London", rewrite.Query)
+}
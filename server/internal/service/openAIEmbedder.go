@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"semki/internal/utils/config"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+
+// openAIEmbeddingDimensions - native dimensions of known models, text-embedding-3 models can return fewer
+var openAIEmbeddingDimensions = map[string]int{
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+}
+
+type openAIEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// openAIEmbedder talks to an OpenAI-compatible /v1/embeddings endpoint. Models it does not know,
+// e.g. ones served by a compatible server, learn their dimensions from the first response
+type openAIEmbedder struct {
+	transport *embedderTransport
+	model     string
+	// shorten - dimensions requested from the model, 0 keeps its native ones
+	shorten    int
+	dimensions atomic.Int64
+}
+
+func NewOpenAIEmbedder(cfg config.EmbedderConfig) IEmbedderService {
+	model := cfg.Model
+	if model == "" || model == "default" {
+		model = defaultOpenAIEmbeddingModel
+	}
+
+	headers := map[string]string{}
+	if cfg.APIKey != "" {
+		headers["Authorization"] = "Bearer " + cfg.APIKey
+	}
+
+	embedder := &openAIEmbedder{
+		transport: newEmbedderTransport("openai embedder", strings.TrimSuffix(cfg.BaseURL, "/"), headers, cfg),
+		model:     model,
+	}
+	if native, ok := openAIEmbeddingDimensions[model]; ok {
+		if strings.HasPrefix(model, "text-embedding-3") && cfg.Dimensions > 0 && cfg.Dimensions < native {
+			embedder.shorten = cfg.Dimensions
+			native = cfg.Dimensions
+		}
+		embedder.dimensions.Store(int64(native))
+	}
+	return embedder
+}
+
+func (s *openAIEmbedder) ModelID() string {
+	return s.model
+}
+
+func (s *openAIEmbedder) Dimensions() int {
+	return int(s.dimensions.Load())
+}
+
+func (s *openAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := s.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (s *openAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	request := openAIEmbeddingRequest{Model: s.model, Input: texts, EncodingFormat: "float", Dimensions: s.shorten}
+	var response openAIEmbeddingResponse
+	if err := s.transport.post(ctx, "/v1/embeddings", request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("embedder returned %d embeddings for %d texts", len(response.Data), len(texts))
+	}
+
+	// Embeddings are matched by index, the order of data is not guaranteed
+	vectors := make([][]float32, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(texts) || vectors[item.Index] != nil {
+			return nil, fmt.Errorf("embedder returned unexpected index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	s.dimensions.Store(int64(len(vectors[0])))
+	return vectors, nil
+}
+
+func (s *openAIEmbedder) EmbedBatchWithIDs(ctx context.Context, texts []TextWithID) ([]EmbeddingWithID, error) {
+	return embedBatchWithIDs(ctx, s, texts)
+}
+
+func (s *openAIEmbedder) Rerank(context.Context, string, []string) ([]float32, error) {
+	return nil, errRerankUnsupported
+}
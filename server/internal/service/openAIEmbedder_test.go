@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"testing"
+)
+
+func configWithProvider(provider string) config.EmbedderConfig {
+	return config.EmbedderConfig{Provider: provider, Dimensions: 32, Model: "default"}
+}
+
+func TestOpenAIEmbedderDimensions(t *testing.T) {
+	cfg := configWithProvider("openai")
+	cfg.Dimensions = 1024
+	assert.Equal(t, 1024, NewOpenAIEmbedder(cfg).Dimensions(), "text-embedding-3 models are shortened")
+
+	cfg.Model = "text-embedding-ada-002"
+	assert.Equal(t, 1536, NewOpenAIEmbedder(cfg).Dimensions(), "ada-002 has fixed dimensions")
+
+	cfg.Model = "nomic-embed-text"
+	assert.Equal(t, 0, NewOpenAIEmbedder(cfg).Dimensions(), "unknown models learn dimensions from responses")
+}
+
+func TestOpenAIEmbedderOrdersByIndex(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	var request openAIEmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		_, _ = w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+
+	cfg := configWithProvider("openai")
+	cfg.BaseURL = server.URL + "/"
+	cfg.APIKey = "secret"
+	cfg.Model = "local-model"
+	embedder := NewOpenAIEmbedder(cfg)
+
+	vectors, err := embedder.EmbedBatch(context.Background(), []string{"go", "kafka"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	assert.Equal(t, "local-model", request.Model)
+	assert.Equal(t, 0, request.Dimensions)
+	assert.Equal(t, 2, embedder.Dimensions())
+}
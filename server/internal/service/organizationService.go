@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -12,6 +13,7 @@ import (
 	"semki/internal/controller/http/v1/dto"
 	"semki/internal/controller/http/v1/routes"
 	"semki/internal/model"
+	"semki/internal/utils/config"
 	"semki/internal/utils/jwtUtils"
 	"semki/internal/utils/mongoUtils"
 	"semki/pkg/lib"
@@ -25,10 +27,11 @@ type organizationService struct {
 	orgRepo       mongo.IOrganizationRepository
 	userRepo      mongo.IUserRepository
 	qdrantService IQdrantService
+	llmConfig     config.LLMConfig
 }
 
-func NewOrganizationService(orgRepo mongo.IOrganizationRepository, userRepo mongo.IUserRepository, qdrantService IQdrantService) routes.IOrganizationService {
-	return &organizationService{orgRepo, userRepo, qdrantService}
+func NewOrganizationService(orgRepo mongo.IOrganizationRepository, userRepo mongo.IUserRepository, qdrantService IQdrantService, llmConfig config.LLMConfig) routes.IOrganizationService {
+	return &organizationService{orgRepo, userRepo, qdrantService, llmConfig}
 }
 
 // CreateOrganization godoc
@@ -284,6 +287,13 @@ func (s *organizationService) PatchOrganization(c *gin.Context) {
 	if req.Title != "" {
 		updates["title"] = req.Title
 	}
+	if req.LLMModel != nil {
+		if err := ValidateLLMModel(s.llmConfig, *req.LLMModel); err != nil {
+			lib.ResponseBadRequest(c, err, "Model is not allowed")
+			return
+		}
+		updates["llmModel"] = *req.LLMModel
+	}
 
 	if len(updates) == 0 {
 		lib.ResponseBadRequest(c, errors.New("no fields to update"), "No fields to update")
@@ -339,6 +349,8 @@ func (s *organizationService) CreateTeam(c *gin.Context) {
 		return
 	}
 
+	s.indexGuidance(ctx, organizationId)
+
 	c.JSON(http.StatusCreated, dto.TeamResponse{Message: "Team created"})
 }
 
@@ -397,6 +409,8 @@ func (s *organizationService) UpdateTeam(c *gin.Context) {
 		return
 	}
 
+	s.indexGuidance(ctx, organizationId)
+
 	c.JSON(http.StatusOK, dto.TeamResponse{Message: "Team updated"})
 }
 
@@ -434,6 +448,8 @@ func (s *organizationService) DeleteTeam(c *gin.Context) {
 		return
 	}
 
+	s.indexGuidance(ctx, organizationId)
+
 	c.JSON(http.StatusOK, dto.TeamResponse{Message: "Team deleted"})
 }
 
@@ -477,6 +493,8 @@ func (s *organizationService) CreateLevel(c *gin.Context) {
 		return
 	}
 
+	s.indexGuidance(ctx, organizationId)
+
 	c.JSON(http.StatusCreated, dto.LevelResponse{Message: "Level created"})
 }
 
@@ -535,6 +553,8 @@ func (s *organizationService) UpdateLevel(c *gin.Context) {
 		return
 	}
 
+	s.indexGuidance(ctx, organizationId)
+
 	c.JSON(http.StatusOK, dto.LevelResponse{Message: "Level updated"})
 }
 
@@ -572,6 +592,8 @@ func (s *organizationService) DeleteLevel(c *gin.Context) {
 		return
 	}
 
+	s.indexGuidance(ctx, organizationId)
+
 	c.JSON(http.StatusOK, dto.LevelResponse{Message: "Level deleted"})
 }
 
@@ -1201,3 +1223,16 @@ func (s *organizationService) InsertMock(c *gin.Context) {
 		},
 	})
 }
+
+// indexGuidance re-embeds team and level descriptions after they change. Failures only degrade ranking
+func (s *organizationService) indexGuidance(ctx context.Context, organizationID primitive.ObjectID) {
+	org, err := s.orgRepo.GetOrganizationByID(ctx, organizationID)
+	if err != nil || org == nil {
+		telemetry.Log.Error("Failed to load organization for guidance indexing", zap.Error(err))
+		return
+	}
+
+	if err := s.qdrantService.IndexGuidance(ctx, org); err != nil {
+		telemetry.Log.Error("Failed to index guidance in Qdrant: " + err.Error())
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"github.com/penglongli/gin-metrics/ginmetrics"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"semki/internal/adapter/mongo"
+	"semki/internal/model"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"time"
+)
+
+// outboxLease - a claimed event is hidden from other workers while it is synced
+const outboxLease = time.Minute
+
+// outboxRecoverBatch - unsynced users enqueued per poll when their event was lost
+const outboxRecoverBatch = 100
+
+//region Metrics
+
+const (
+	outboxPendingMetric   = "outbox_pending_events"
+	outboxFailedMetric    = "outbox_failed_events"
+	outboxLagMetric       = "outbox_lag_seconds"
+	outboxProcessedMetric = "outbox_processed_total"
+)
+
+var outboxResults = struct {
+	Synced  string
+	Retried string
+	Failed  string
+}{
+	Synced:  "synced",
+	Retried: "retried",
+	Failed:  "failed",
+}
+
+func registerOutboxMetrics() {
+	telemetry.AddMetric(&ginmetrics.Metric{
+		Type:        ginmetrics.Gauge,
+		Name:        outboxPendingMetric,
+		Description: "Users waiting to be synced to Qdrant",
+		Labels:      []string{},
+	})
+	telemetry.AddMetric(&ginmetrics.Metric{
+		Type:        ginmetrics.Gauge,
+		Name:        outboxFailedMetric,
+		Description: "Outbox events that ran out of attempts",
+		Labels:      []string{},
+	})
+	telemetry.AddMetric(&ginmetrics.Metric{
+		Type:        ginmetrics.Gauge,
+		Name:        outboxLagMetric,
+		Description: "Age of the oldest pending outbox event",
+		Labels:      []string{},
+	})
+	telemetry.AddMetric(&ginmetrics.Metric{
+		Type:        ginmetrics.Counter,
+		Name:        outboxProcessedMetric,
+		Description: "Outbox events by result: synced, retried or failed",
+		Labels:      []string{"result"},
+	})
+}
+
+//endregion
+
+// IOutboxService keeps Qdrant in sync with user changes in Mongo
+type IOutboxService interface {
+	// Write runs change and records that the user has to be synced. The user repository marks the user document
+	// in the same write, so the user is recovered when the event is lost on a deployment without transactions
+	Write(ctx context.Context, userID primitive.ObjectID, change func(ctx context.Context) error) error
+	// Run drains the outbox until ctx is done
+	Run(ctx context.Context)
+}
+
+type outboxService struct {
+	repo          mongo.IOutboxRepository
+	tx            mongo.ITransactor
+	userRepo      mongo.IUserRepository
+	qdrantService IQdrantService
+	cfg           config.OutboxConfig
+	wake          chan struct{}
+}
+
+func NewOutboxService(repo mongo.IOutboxRepository, tx mongo.ITransactor, userRepo mongo.IUserRepository, qdrantService IQdrantService, cfg config.OutboxConfig) IOutboxService {
+	registerOutboxMetrics()
+	return &outboxService{repo, tx, userRepo, qdrantService, cfg, make(chan struct{}, 1)}
+}
+
+func (s *outboxService) Write(ctx context.Context, userID primitive.ObjectID, change func(ctx context.Context) error) error {
+	err := s.tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := change(ctx); err != nil {
+			return err
+		}
+		return s.repo.Enqueue(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The worker does not wait for the next poll
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *outboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.recover(ctx)
+		s.drain(ctx)
+		s.reportStats(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// recover enqueues users that were written but never synced, e.g. the process stopped between the change and its event.
+// Users written within the last lease are left to their own event
+func (s *outboxService) recover(ctx context.Context) {
+	userIDs, err := s.userRepo.GetUnsyncedUserIDs(ctx, time.Now().Add(-outboxLease), outboxRecoverBatch)
+	if err != nil {
+		telemetry.Log.Error("Failed to find unsynced users", zap.Error(err))
+		return
+	}
+	for _, userID := range userIDs {
+		if err := s.repo.EnqueueMissing(ctx, userID); err != nil {
+			telemetry.Log.Error("Failed to enqueue unsynced user "+userID.Hex(), zap.Error(err))
+			return
+		}
+	}
+}
+
+// drain syncs due events until none is left
+func (s *outboxService) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		event, err := s.repo.Claim(ctx, outboxLease)
+		if err != nil {
+			telemetry.Log.Error("Failed to claim outbox event", zap.Error(err))
+			return
+		}
+		if event == nil {
+			return
+		}
+		s.process(ctx, event)
+	}
+}
+
+func (s *outboxService) process(ctx context.Context, event *model.OutboxEvent) {
+	token, err := s.syncUser(ctx, event.UserID)
+	if err == nil {
+		if err := s.repo.Complete(ctx, event.ID); err != nil {
+			telemetry.Log.Error("Failed to complete outbox event "+event.ID.Hex(), zap.Error(err))
+		}
+		s.clearSyncToken(ctx, event.UserID, token)
+		_ = ginmetrics.GetMonitor().GetMetric(outboxProcessedMetric).Inc([]string{outboxResults.Synced})
+		return
+	}
+
+	if event.Attempts >= s.cfg.MaxAttempts {
+		telemetry.Log.Error("Gave up syncing user "+event.UserID.Hex()+" to Qdrant", zap.Error(err))
+		if err := s.repo.Fail(ctx, event.ID, err.Error()); err != nil {
+			telemetry.Log.Error("Failed to mark outbox event "+event.ID.Hex()+" as failed", zap.Error(err))
+		}
+		// The failed event is kept for inspection, the user is not recovered again until the next write
+		s.clearSyncToken(ctx, event.UserID, token)
+		_ = ginmetrics.GetMonitor().GetMetric(outboxProcessedMetric).Inc([]string{outboxResults.Failed})
+		return
+	}
+
+	delay := outboxBackoff(event.Attempts, s.cfg.RetryBase, s.cfg.RetryMax)
+	telemetry.Log.Warn("Failed to sync user "+event.UserID.Hex()+" to Qdrant, retrying in "+delay.String(), zap.Error(err))
+	if err := s.repo.Retry(ctx, event.ID, time.Now().Add(delay), err.Error()); err != nil {
+		telemetry.Log.Error("Failed to reschedule outbox event "+event.ID.Hex(), zap.Error(err))
+	}
+	_ = ginmetrics.GetMonitor().GetMetric(outboxProcessedMetric).Inc([]string{outboxResults.Retried})
+}
+
+// syncUser indexes the current state of the user, deleted users are removed from Qdrant.
+// Returns the sync token of the state that was synced
+func (s *outboxService) syncUser(ctx context.Context, userID primitive.ObjectID) (primitive.ObjectID, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if user == nil {
+		return primitive.NilObjectID, s.qdrantService.DeleteUser(ctx, userID.Hex())
+	}
+	if user.Status == model.UserStatuses.DELETED {
+		return user.SyncToken, s.qdrantService.DeleteUser(ctx, userID.Hex())
+	}
+	return user.SyncToken, s.qdrantService.IndexUser(ctx, user)
+}
+
+func (s *outboxService) clearSyncToken(ctx context.Context, userID primitive.ObjectID, token primitive.ObjectID) {
+	if token.IsZero() {
+		return
+	}
+	if err := s.userRepo.ClearSyncToken(ctx, userID, token); err != nil {
+		telemetry.Log.Error("Failed to clear sync token of user "+userID.Hex(), zap.Error(err))
+	}
+}
+
+func (s *outboxService) reportStats(ctx context.Context) {
+	stats, err := s.repo.Stats(ctx)
+	if err != nil {
+		telemetry.Log.Error("Failed to read outbox stats", zap.Error(err))
+		return
+	}
+
+	lag := 0.0
+	if stats.OldestPending != nil {
+		lag = time.Since(*stats.OldestPending).Seconds()
+	}
+	monitor := ginmetrics.GetMonitor()
+	_ = monitor.GetMetric(outboxPendingMetric).SetGaugeValue([]string{}, float64(stats.Pending))
+	_ = monitor.GetMetric(outboxFailedMetric).SetGaugeValue([]string{}, float64(stats.Failed))
+	_ = monitor.GetMetric(outboxLagMetric).SetGaugeValue([]string{}, lag)
+}
+
+// outboxBackoff - delay before the next attempt, doubles from base and is capped by maxDelay
+func outboxBackoff(attempt int, base time.Duration, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
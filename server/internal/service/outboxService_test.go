@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"semki/internal/adapter/mongo"
+	"semki/internal/model"
+	"semki/internal/utils/config"
+	"semki/pkg/telemetry"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	base := time.Second
+	maxDelay := 10 * time.Second
+
+	assert.Equal(t, time.Second, outboxBackoff(1, base, maxDelay))
+	assert.Equal(t, 2*time.Second, outboxBackoff(2, base, maxDelay))
+	assert.Equal(t, 8*time.Second, outboxBackoff(4, base, maxDelay))
+	assert.Equal(t, maxDelay, outboxBackoff(5, base, maxDelay))
+	assert.Equal(t, maxDelay, outboxBackoff(1000, base, maxDelay))
+}
+
+type outboxRepo struct {
+	mongo.IOutboxRepository
+	enqueued  []primitive.ObjectID
+	completed []primitive.ObjectID
+}
+
+func (r *outboxRepo) EnqueueMissing(_ context.Context, userID primitive.ObjectID) error {
+	r.enqueued = append(r.enqueued, userID)
+	return nil
+}
+
+func (r *outboxRepo) Complete(_ context.Context, id primitive.ObjectID) error {
+	r.completed = append(r.completed, id)
+	return nil
+}
+
+func (r *outboxRepo) Retry(context.Context, primitive.ObjectID, time.Time, string) error {
+	return nil
+}
+
+type outboxUsers struct {
+	mongo.IUserRepository
+	user     *model.User
+	unsynced []primitive.ObjectID
+	cleared  map[primitive.ObjectID]primitive.ObjectID
+}
+
+func (r *outboxUsers) GetUserByID(context.Context, primitive.ObjectID) (*model.User, error) {
+	return r.user, nil
+}
+
+func (r *outboxUsers) GetUnsyncedUserIDs(context.Context, time.Time, int64) ([]primitive.ObjectID, error) {
+	return r.unsynced, nil
+}
+
+func (r *outboxUsers) ClearSyncToken(_ context.Context, id primitive.ObjectID, token primitive.ObjectID) error {
+	r.cleared[id] = token
+	return nil
+}
+
+type outboxQdrant struct {
+	IQdrantService
+	err error
+}
+
+func (s *outboxQdrant) IndexUser(context.Context, *model.User) error {
+	return s.err
+}
+
+func TestOutboxRecoversUnsyncedUsers(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	repo := &outboxRepo{}
+	users := &outboxUsers{unsynced: []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID()}}
+	s := &outboxService{repo: repo, userRepo: users}
+
+	s.recover(context.Background())
+
+	assert.Equal(t, users.unsynced, repo.enqueued)
+}
+
+func TestOutboxClearsSyncedToken(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	user := &model.User{ID: primitive.NewObjectID(), Status: model.UserStatuses.ACTIVE, SyncToken: primitive.NewObjectID()}
+	event := &model.OutboxEvent{ID: primitive.NewObjectID(), UserID: user.ID, Attempts: 1}
+
+	t.Run("synced", func(t *testing.T) {
+		repo := &outboxRepo{}
+		users := &outboxUsers{user: user, cleared: map[primitive.ObjectID]primitive.ObjectID{}}
+		s := &outboxService{repo: repo, userRepo: users, qdrantService: &outboxQdrant{}, cfg: config.OutboxConfig{MaxAttempts: 3}}
+
+		s.process(context.Background(), event)
+
+		assert.Equal(t, []primitive.ObjectID{event.ID}, repo.completed)
+		assert.Equal(t, user.SyncToken, users.cleared[user.ID])
+	})
+
+	t.Run("retried", func(t *testing.T) {
+		repo := &outboxRepo{}
+		users := &outboxUsers{user: user, cleared: map[primitive.ObjectID]primitive.ObjectID{}}
+		qdrant := &outboxQdrant{err: errors.New("qdrant is down")}
+		s := &outboxService{repo: repo, userRepo: users, qdrantService: qdrant, cfg: config.OutboxConfig{MaxAttempts: 3}}
+
+		s.process(context.Background(), event)
+
+		assert.Empty(t, repo.completed)
+		assert.Empty(t, users.cleared)
+	})
+}
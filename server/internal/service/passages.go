@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/model"
+	"semki/pkg/lib"
+	"sort"
+)
+
+const (
+	// maxHighlights - passages returned with every result
+	maxHighlights = 3
+	// passagesPerUser - passages fetched per requested user, so a user with many matching passages doesn't crowd out others
+	passagesPerUser = 5
+)
+
+// indexPassages splits the description and replaces the passage points of the user
+func (s *qdrantService) indexPassages(ctx context.Context, repo qdrant.IQdrantRepository, user *model.User) error {
+	texts := lib.SplitPassages(user.Semantic.Description, s.searchCfg.PassageWords)
+
+	passages := make([]qdrant.Passage, 0, len(texts))
+	if len(texts) > 0 {
+		vectors, err := s.embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("passage embedding failed: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedder returned %d vectors for %d passages", len(vectors), len(texts))
+		}
+		for i, text := range texts {
+			passages = append(passages, qdrant.Passage{Index: i, Text: text, Vector: vectors[i]})
+		}
+	}
+
+	return repo.IndexUserPassages(ctx, user, passages)
+}
+
+// rankByPassages ranks users by their passages aggregated with filters.Passages
+func (s *qdrantService) rankByPassages(ctx context.Context, vector []float32, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, error) {
+	passageFilters := filters
+	passageFilters.Limit = filters.Limit * passagesPerUser
+	hits, err := s.repo.SearchPassages(ctx, vector, passageFilters, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := aggregatePassages(hits, filters.Passages)
+	if uint64(len(results)) > filters.Limit {
+		results = results[:filters.Limit]
+	}
+	return results, nil
+}
+
+// attachHighlights adds the best passages of every result that has none yet
+func (s *qdrantService) attachHighlights(ctx context.Context, vector []float32, filters qdrant.SearchFilters, results []qdrant.VectorSearchResult) error {
+	userIDs := make([]string, 0, len(results))
+	for _, r := range results {
+		if len(r.Passages) == 0 {
+			userIDs = append(userIDs, r.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	passageFilters := filters
+	passageFilters.Limit = uint64(len(userIDs) * passagesPerUser)
+	hits, err := s.repo.SearchPassages(ctx, vector, passageFilters, userIDs)
+	if err != nil {
+		return err
+	}
+
+	byUser := aggregatePassages(hits, qdrant.PassageAggregations.Max)
+	passages := make(map[string][]qdrant.PassageMatch, len(byUser))
+	for _, r := range byUser {
+		passages[r.UserID] = r.Passages
+	}
+	for i := range results {
+		if len(results[i].Passages) == 0 {
+			results[i].Passages = passages[results[i].UserID]
+		}
+	}
+	return nil
+}
+
+// aggregatePassages groups passage hits per user. Score is the best passage (max)
+// or all matched passages together (sum), the best passages are kept as highlights
+func aggregatePassages(hits []qdrant.PassageHit, aggregation qdrant.PassageAggregation) []qdrant.VectorSearchResult {
+	byUser := make(map[string]*qdrant.VectorSearchResult)
+	order := make([]string, 0)
+	for _, hit := range hits {
+		result, ok := byUser[hit.UserID]
+		if !ok {
+			result = &qdrant.VectorSearchResult{
+				UserID:   hit.UserID,
+				Team:     hit.Team,
+				Level:    hit.Level,
+				Location: hit.Location,
+			}
+			byUser[hit.UserID] = result
+			order = append(order, hit.UserID)
+		}
+
+		if aggregation == qdrant.PassageAggregations.Sum {
+			result.Score += hit.Score
+		} else if len(result.Passages) == 0 || hit.Score > result.Score {
+			result.Score = hit.Score
+		}
+		result.Passages = append(result.Passages, hit.PassageMatch)
+	}
+
+	results := make([]qdrant.VectorSearchResult, 0, len(order))
+	for _, userID := range order {
+		result := byUser[userID]
+		sort.SliceStable(result.Passages, func(i, j int) bool {
+			return result.Passages[i].Score > result.Passages[j].Score
+		})
+		if len(result.Passages) > maxHighlights {
+			result.Passages = result.Passages[:maxHighlights]
+		}
+		results = append(results, *result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
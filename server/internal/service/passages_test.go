@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"semki/internal/adapter/qdrant"
+	"testing"
+)
+
+func TestAggregatePassages(t *testing.T) {
+	hit := func(userID string, index int, score float32) qdrant.PassageHit {
+		return qdrant.PassageHit{UserID: userID, PassageMatch: qdrant.PassageMatch{Index: index, Score: score}}
+	}
+	hits := []qdrant.PassageHit{
+		hit("a", 0, 0.9),
+		hit("b", 0, 0.6),
+		hit("b", 1, 0.55),
+		hit("b", 2, 0.5),
+		hit("b", 3, 0.1),
+	}
+
+	byMax := aggregatePassages(hits, qdrant.PassageAggregations.Max)
+	assert.Equal(t, []string{"a", "b"}, userIDs(byMax))
+	assert.Equal(t, float32(0.9), byMax[0].Score)
+	assert.Len(t, byMax[1].Passages, maxHighlights)
+	assert.Equal(t, 0, byMax[1].Passages[0].Index)
+
+	bySum := aggregatePassages(hits, qdrant.PassageAggregations.Sum)
+	assert.Equal(t, []string{"b", "a"}, userIDs(bySum))
+	assert.InDelta(t, 1.75, bySum[0].Score, 1e-6)
+}
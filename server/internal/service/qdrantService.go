@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -10,51 +11,261 @@ import (
 	"semki/internal/adapter/qdrant"
 	"semki/internal/controller/http/v1/dto"
 	"semki/internal/model"
+	"semki/internal/utils/config"
 	"semki/internal/utils/jwtUtils"
+	"semki/internal/utils/mongoUtils"
 	"semki/pkg/lib"
+	"semki/pkg/telemetry"
+	"sort"
+	"strconv"
+	"time"
 )
 
 type IQdrantService interface {
 	IndexUser(ctx context.Context, user *model.User) error
 	UpdateUser(ctx context.Context, user *model.User) error
 	DeleteUser(ctx context.Context, id string) error
-	SearchUsers(ctx context.Context, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, error)
+	// SearchUsers also returns the query vector, nil when the search needed none
+	SearchUsers(ctx context.Context, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, []float32, error)
+	RecommendUsers(ctx context.Context, positive []string, negative []string, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, error)
+	CountFacets(ctx context.Context, filters qdrant.SearchFilters, vector []float32) (qdrant.FacetCounts, error)
 	ReIndexReq(c *gin.Context)
+	GetReindexJob(c *gin.Context)
+	CancelReindexJob(c *gin.Context)
+	CheckIndexDrift(c *gin.Context)
+	RepairIndexDrift(c *gin.Context)
+	ListIndexVersions(c *gin.Context)
+	ActivateIndexVersion(c *gin.Context)
+	GetEmbeddingModelStats(c *gin.Context)
+	ConfirmEmbeddingModel(c *gin.Context)
 	ReIndexFunc(ctx context.Context, organizationID primitive.ObjectID) (int, error)
+	BackfillOrganizations(ctx context.Context) (int, error)
+	IndexGuidance(ctx context.Context, org *model.Organization) error
 }
 
+// guidanceOverFetch - blending reorders candidates, so more than limit are fetched from Qdrant
+const guidanceOverFetch = 3
+
+// diversityOverFetch - MMR needs alternatives to the near-duplicates at the top
+const diversityOverFetch = 3
+
 type qdrantService struct {
-	repo     qdrant.IQdrantRepository
-	userRepo mongo.IUserRepository
-	embedder IEmbedderService
+	repo      qdrant.IQdrantRepository
+	userRepo  mongo.IUserRepository
+	orgRepo   mongo.IOrganizationRepository
+	embedder  IEmbedderService
+	searchCfg config.SearchConfig
+	jobs      *reindexJobs
 }
 
-func NewQdrantService(repo qdrant.IQdrantRepository, userRepo mongo.IUserRepository, embedder IEmbedderService) IQdrantService {
-	return &qdrantService{repo, userRepo, embedder}
+func NewQdrantService(repo qdrant.IQdrantRepository, userRepo mongo.IUserRepository, orgRepo mongo.IOrganizationRepository, embedder IEmbedderService, searchCfg config.SearchConfig) IQdrantService {
+	return &qdrantService{repo, userRepo, orgRepo, embedder, searchCfg, newReindexJobs()}
 }
 
 func (s *qdrantService) IndexUser(ctx context.Context, user *model.User) error {
-	vector, err := s.embedder.Embed(user.Semantic.Description)
+	return s.indexUserIn(ctx, s.repo, user)
+}
+
+// indexUserIn indexes the user description and its passages into repo, the live or a building version
+func (s *qdrantService) indexUserIn(ctx context.Context, repo qdrant.IQdrantRepository, user *model.User) error {
+	vector, err := s.embedder.Embed(ctx, user.Semantic.Description)
 	if err != nil {
 		return fmt.Errorf("embedding failed: %w", err)
 	}
-	return s.repo.IndexUserWithVector(ctx, user, vector)
+	if err := repo.IndexUserWithVector(ctx, user, vector); err != nil {
+		return err
+	}
+	return s.indexPassages(ctx, repo, user)
 }
 
 func (s *qdrantService) UpdateUser(ctx context.Context, user *model.User) error {
-	vector, err := s.embedder.Embed(user.Semantic.Description)
+	vector, err := s.embedder.Embed(ctx, user.Semantic.Description)
 	if err != nil {
 		return fmt.Errorf("embedding failed: %w", err)
 	}
-	return s.repo.UpdateUserWithVector(ctx, user, vector)
+	if err := s.repo.UpdateUserWithVector(ctx, user, vector); err != nil {
+		return err
+	}
+	return s.indexPassages(ctx, s.repo, user)
 }
 
-func (s *qdrantService) SearchUsers(ctx context.Context, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, error) {
-	vector, err := s.embedder.Embed(filters.Query)
+func (s *qdrantService) SearchUsers(ctx context.Context, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, []float32, error) {
+	results, vector, err := s.rankUsers(ctx, filters)
 	if err != nil {
-		return nil, fmt.Errorf("embedding failed: %w", err)
+		return nil, nil, err
+	}
+
+	if filters.Diversity.Enabled() {
+		results = diversifyResults(results, filters.Diversity, int(filters.Limit))
 	}
-	return s.repo.SearchUserByVector(ctx, vector, filters)
+	if uint64(len(results)) > filters.Limit {
+		results = results[:filters.Limit]
+	}
+
+	if len(vector) > 0 {
+		if err := s.attachHighlights(ctx, vector, filters, results); err != nil {
+			// Highlights are decoration, the ranking is still valid
+			telemetry.Log.Warn("Failed to attach passage highlights: " + err.Error())
+		}
+	}
+	return results, vector, nil
+}
+
+// rankUsers returns candidates by relevance, over-fetched when a later step reorders them,
+// and the query vector if one was embedded
+func (s *qdrantService) rankUsers(ctx context.Context, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, []float32, error) {
+	candidates := filters
+	if filters.Diversity.Enabled() {
+		candidates.Limit = filters.Limit * diversityOverFetch
+	}
+
+	// Lexical search needs no embedding at all
+	if filters.Mode == qdrant.SearchModes.Lexical {
+		results, err := s.repo.SearchUserLexical(ctx, candidates)
+		return results, nil, err
+	}
+
+	vector, err := s.embedder.Embed(ctx, filters.Query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("embedding failed: %w", err)
+	}
+
+	var negative []float32
+	if filters.NegativeQuery != "" {
+		negative, err = s.embedder.Embed(ctx, filters.NegativeQuery)
+		if err != nil {
+			return nil, nil, fmt.Errorf("negative embedding failed: %w", err)
+		}
+	}
+
+	if filters.Mode == qdrant.SearchModes.Hybrid {
+		results, err := s.repo.SearchUserHybrid(ctx, vector, negative, candidates)
+		return results, vector, err
+	}
+
+	byPassages := len(negative) == 0 &&
+		(filters.Passages == qdrant.PassageAggregations.Max || filters.Passages == qdrant.PassageAggregations.Sum)
+
+	// Guidance is blended with cosine scores only, RRF, recommend and summed scores are not comparable with similarities
+	if (s.searchCfg.TeamWeight <= 0 && s.searchCfg.LevelWeight <= 0) || len(negative) > 0 || filters.Passages == qdrant.PassageAggregations.Sum {
+		if byPassages {
+			results, err := s.rankByPassages(ctx, vector, candidates)
+			return results, vector, err
+		}
+		results, err := s.repo.SearchUserByVector(ctx, vector, negative, candidates)
+		return results, vector, err
+	}
+
+	candidates.Limit = max(candidates.Limit, filters.Limit*guidanceOverFetch)
+	var results []qdrant.VectorSearchResult
+	if byPassages {
+		results, err = s.rankByPassages(ctx, vector, candidates)
+	} else {
+		results, err = s.repo.SearchUserByVector(ctx, vector, nil, candidates)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	guidance, err := s.repo.SearchGuidance(ctx, filters.OrganizationID, vector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return blendGuidanceScores(results, guidance, s.searchCfg), vector, nil
+}
+
+func (s *qdrantService) RecommendUsers(ctx context.Context, positive []string, negative []string, filters qdrant.SearchFilters) ([]qdrant.VectorSearchResult, error) {
+	return s.repo.RecommendUsers(ctx, positive, negative, filters)
+}
+
+// CountFacets counts teams, levels and locations of candidates above the configured similarity threshold
+// before the filters picked by the user and the chat exclusions apply. vector is the query vector of the
+// search if it has one already
+func (s *qdrantService) CountFacets(ctx context.Context, filters qdrant.SearchFilters, vector []float32) (qdrant.FacetCounts, error) {
+	candidates := qdrant.SearchFilters{
+		OrganizationID: filters.OrganizationID,
+		Query:          filters.Query,
+		Mode:           filters.Mode,
+		Limit:          uint64(s.searchCfg.FacetCandidates),
+	}
+
+	if filters.Mode == qdrant.SearchModes.Lexical {
+		return s.repo.CountFacets(ctx, nil, candidates, 0)
+	}
+
+	if len(vector) == 0 {
+		var err error
+		vector, err = s.embedder.Embed(ctx, filters.Query)
+		if err != nil {
+			return qdrant.FacetCounts{}, fmt.Errorf("embedding failed: %w", err)
+		}
+	}
+	return s.repo.CountFacets(ctx, vector, candidates, float32(s.searchCfg.FacetThreshold))
+}
+
+// blendGuidanceScores mixes the user description similarity with the query similarity
+// to the user's team and level guidance. Missing guidance is left out of the weighted mean
+func blendGuidanceScores(results []qdrant.VectorSearchResult, guidance qdrant.GuidanceScores, weights config.SearchConfig) []qdrant.VectorSearchResult {
+	for i := range results {
+		score := weights.UserWeight * float64(results[i].Score)
+		total := weights.UserWeight
+
+		if teamScore, ok := guidance[results[i].Team]; ok {
+			score += weights.TeamWeight * float64(teamScore)
+			total += weights.TeamWeight
+		}
+		if levelScore, ok := guidance[results[i].Level]; ok {
+			score += weights.LevelWeight * float64(levelScore)
+			total += weights.LevelWeight
+		}
+
+		if total > 0 {
+			results[i].Score = float32(score / total)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// IndexGuidance embeds team and level descriptions of the organization
+func (s *qdrantService) IndexGuidance(ctx context.Context, org *model.Organization) error {
+	texts := make([]string, 0, len(org.Semantic.Teams)+len(org.Semantic.Levels))
+	guidance := make([]qdrant.GuidanceVector, 0, cap(texts))
+
+	for _, t := range org.Semantic.Teams {
+		if t.Description == "" {
+			continue
+		}
+		texts = append(texts, t.Name+". "+t.Description)
+		guidance = append(guidance, qdrant.GuidanceVector{Kind: qdrant.GuidanceKinds.Team, RefID: t.ID.Hex()})
+	}
+
+	for _, l := range org.Semantic.Levels {
+		if l.Description == "" {
+			continue
+		}
+		texts = append(texts, l.Name+". "+l.Description)
+		guidance = append(guidance, qdrant.GuidanceVector{Kind: qdrant.GuidanceKinds.Level, RefID: l.ID.Hex()})
+	}
+
+	if len(texts) > 0 {
+		vectors, err := s.embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("embedding failed: %w", err)
+		}
+		if len(vectors) != len(guidance) {
+			return fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(guidance))
+		}
+		for i := range guidance {
+			guidance[i].Vector = vectors[i]
+		}
+	}
+
+	return s.repo.IndexGuidance(ctx, org.ID.Hex(), guidance)
 }
 
 func (s *qdrantService) DeleteUser(ctx context.Context, id string) error {
@@ -64,15 +275,17 @@ func (s *qdrantService) DeleteUser(ctx context.Context, id string) error {
 // ReIndexReq godoc
 //
 //	@Summary		Re-index all users
-//	@Description	Retrieves all users from the database and reindexes them in Qdrant with fresh embeddings.
+//	@Description	Starts a background job that re-embeds all users of the organization. Poll GET /api/v1/reindex/{jobId} for progress.
+//	@Description	The new version goes live only if every user was indexed, with acceptFailures the failed users keep their live points.
 //	@Tags			qdrant
 //	@Produce		json
-//	@Success		200	{object}	map[string]interface{}	"Number of reindexed users"
-//	@Failure		500	{object}	map[string]string		"Failed to fetch users"
+//	@Param			acceptFailures	query		bool				false	"Commit the version even if some users fail"
+//	@Success		202				{object}	dto.ReindexJob		"Started job"
+//	@Failure		400				{object}	lib.ErrorResponse	"Invalid acceptFailures"
+//	@Failure		409				{object}	dto.ReindexJob		"Job already running for the organization"
 //	@Router			/api/v1/reindex [post]
 //	@Security		BearerAuth
 func (s *qdrantService) ReIndexReq(c *gin.Context) {
-	ctx := c.Request.Context()
 	userClaims, _ := c.Get(jwtUtils.IdentityKey)
 	if userClaims == nil {
 		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "unauthorized"})
@@ -85,33 +298,208 @@ func (s *qdrantService) ReIndexReq(c *gin.Context) {
 	}
 	organizationID := claims.OrganizationID
 
-	totalIndexed, err := s.ReIndexFunc(ctx, organizationID)
+	acceptFailures, err := strconv.ParseBool(c.DefaultQuery("acceptFailures", "false"))
 	if err != nil {
-		lib.ResponseInternalServerError(c, err, "failed to fetch users")
+		lib.ResponseBadRequest(c, err, "Invalid acceptFailures")
+		return
+	}
+
+	// The job outlives the request, only the values of its context are kept
+	job, ctx, err := s.jobs.start(context.WithoutCancel(c.Request.Context()), organizationID.Hex())
+	if errors.Is(err, ErrReindexRunning) {
+		c.JSON(http.StatusConflict, job.snapshot())
+		return
+	}
+	job.update(func(state *dto.ReindexJob) {
+		state.AcceptFailures = acceptFailures
+	})
+	go s.runReindex(ctx, job, organizationID)
+
+	c.JSON(http.StatusAccepted, job.snapshot())
+}
+
+// GetReindexJob godoc
+//
+//	@Summary		Reindex job progress
+//	@Description	Processed, failed and total users of a reindex job with the failure reasons.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Param			jobId	path		string	true	"Job ID"
+//	@Success		200		{object}	dto.ReindexJob
+//	@Failure		404		{object}	lib.ErrorResponse	"Job not found"
+//	@Router			/api/v1/reindex/{jobId} [get]
+//	@Security		BearerAuth
+func (s *qdrantService) GetReindexJob(c *gin.Context) {
+	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
+	if !ok || userClaimsRaw == nil {
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
 		return
 	}
+	claims := userClaimsRaw.(*jwtUtils.UserClaims)
 
-	c.JSON(200, gin.H{"message": fmt.Sprintf("reindexed %d users", totalIndexed)})
+	job, ok := s.jobs.get(c.Param("jobId"), claims.OrganizationID.Hex())
+	if !ok {
+		lib.ResponseNotFound(c, "Reindex job not found")
+		return
+	}
+	c.JSON(http.StatusOK, job.snapshot())
+}
+
+// CancelReindexJob godoc
+//
+//	@Summary		Cancel a reindex job
+//	@Description	Stops the job and drops the collection version it was building. Search keeps using the live version.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Param			jobId	path		string	true	"Job ID"
+//	@Success		200		{object}	dto.ReindexJob		"Cancelled job"
+//	@Failure		404		{object}	lib.ErrorResponse	"Job not found"
+//	@Failure		409		{object}	dto.ReindexJob		"Job already finished"
+//	@Router			/api/v1/reindex/{jobId} [delete]
+//	@Security		BearerAuth
+func (s *qdrantService) CancelReindexJob(c *gin.Context) {
+	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
+	if !ok || userClaimsRaw == nil {
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
+		return
+	}
+	claims := userClaimsRaw.(*jwtUtils.UserClaims)
+
+	job, ok := s.jobs.get(c.Param("jobId"), claims.OrganizationID.Hex())
+	if !ok {
+		lib.ResponseNotFound(c, "Reindex job not found")
+		return
+	}
+	if job.snapshot().FinishedAt != nil {
+		c.JSON(http.StatusConflict, job.snapshot())
+		return
+	}
+
+	job.cancel()
+	select {
+	case <-job.done:
+	case <-c.Request.Context().Done():
+	}
+	c.JSON(http.StatusOK, job.snapshot())
 }
 
+// ReIndexFunc runs a reindex job in the caller goroutine and returns the number of indexed users
 func (s *qdrantService) ReIndexFunc(ctx context.Context, organizationID primitive.ObjectID) (int, error) {
+	job, jobCtx, err := s.jobs.start(ctx, organizationID.Hex())
+	if err != nil {
+		return 0, err
+	}
+	s.runReindex(jobCtx, job, organizationID)
+
+	state := job.snapshot()
+	indexed := state.Processed - state.Failed
+	if state.Status != dto.ReindexJobStatuses.Completed {
+		return indexed, fmt.Errorf("reindex job %s %s: %s", state.ID, state.Status, state.Error)
+	}
+	return indexed, nil
+}
+
+func (s *qdrantService) runReindex(ctx context.Context, job *reindexJob, organizationID primitive.ObjectID) {
+	defer s.jobs.release(job)
+
+	err := s.reindex(ctx, job, organizationID)
+	job.finish(ctx, err)
+
+	state := job.snapshot()
+	if err != nil {
+		telemetry.Log.Error(fmt.Sprintf("Reindex job %s %s: %v", state.ID, state.Status, err))
+		return
+	}
+	telemetry.Log.Info(fmt.Sprintf("Reindex job %s completed, %d of %d users, %d failed", state.ID, state.Processed, state.Total, state.Failed))
+}
+
+// reindex re-embeds the organization into a new collection version, blue/green:
+// other organizations are copied as they are, search keeps using the live version until the aliases switch
+func (s *qdrantService) reindex(ctx context.Context, job *reindexJob, organizationID primitive.ObjectID) error {
+	org, err := s.orgRepo.GetOrganizationByID(ctx, organizationID)
+	if err != nil {
+		return err
+	}
+	if org == nil {
+		return fmt.Errorf("organization %s not found", organizationID.Hex())
+	}
+	if err := s.IndexGuidance(ctx, org); err != nil {
+		return err
+	}
+
+	version, err := s.beginVersion(ctx)
+	if err != nil {
+		return err
+	}
+	job.update(func(state *dto.ReindexJob) {
+		state.Status = dto.ReindexJobStatuses.Running
+		state.Version = version.Version
+	})
+
+	if err := s.buildVersion(ctx, job, version, organizationID); err != nil {
+		if abortErr := s.repo.AbortVersion(context.WithoutCancel(ctx), version); abortErr != nil {
+			telemetry.Log.Error("Failed to abort collection version " + version.Version + ": " + abortErr.Error())
+		}
+		return err
+	}
+
+	if err := s.repo.CommitVersion(context.WithoutCancel(ctx), version); err != nil {
+		return err
+	}
+	telemetry.Log.Info("Collection version " + version.Version + " is live")
+	return nil
+}
+
+// beginVersion waits while another organization builds its version, the job stays queued
+func (s *qdrantService) beginVersion(ctx context.Context) (qdrant.CollectionVersion, error) {
+	for {
+		version, err := s.repo.BeginVersion(ctx)
+		if !errors.Is(err, qdrant.ErrBuildInProgress) {
+			return version, err
+		}
+		select {
+		case <-ctx.Done():
+			return qdrant.CollectionVersion{}, ctx.Err()
+		case <-time.After(buildWaitInterval):
+		}
+	}
+}
+
+func (s *qdrantService) buildVersion(ctx context.Context, job *reindexJob, version qdrant.CollectionVersion, organizationID primitive.ObjectID) error {
+	copied, err := s.repo.CopyPoints(ctx, version, organizationID.Hex())
+	if err != nil {
+		return err
+	}
+	telemetry.Log.Info(fmt.Sprintf("Copied %d users of other organizations into %s", copied, version.Version))
+
+	target := s.repo.InVersion(version)
 	limit := 100
 	page := 1
-	totalIndexed := 0
+	var failed []string
 
 	for {
 		users, total, err := s.userRepo.GetUsersByOrganization(ctx, organizationID, "", page, limit)
 		if err != nil {
-			return totalIndexed, err
+			return err
 		}
+		job.update(func(state *dto.ReindexJob) {
+			state.Total = int(total)
+		})
 
 		if len(users) == 0 {
 			break
 		}
 
 		for _, user := range users {
-			_ = s.IndexUser(ctx, user)
-			totalIndexed++
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err := s.indexUserIn(ctx, target, user)
+			if err != nil {
+				telemetry.Log.Error("Failed to reindex user " + user.ID.Hex() + " in Qdrant: " + err.Error())
+				failed = append(failed, user.ID.Hex())
+			}
+			job.processed(user.ID.Hex(), err)
 		}
 
 		if int64(page*limit) >= total {
@@ -119,5 +507,215 @@ func (s *qdrantService) ReIndexFunc(ctx context.Context, organizationID primitiv
 		}
 		page++
 	}
-	return totalIndexed, nil
+
+	if len(failed) == 0 {
+		return nil
+	}
+	if !job.snapshot().AcceptFailures {
+		return fmt.Errorf("%w: %d users failed", ErrReindexIncomplete, len(failed))
+	}
+	carried, err := s.repo.CopyUsers(ctx, version, failed)
+	if err != nil {
+		return err
+	}
+	telemetry.Log.Warn(fmt.Sprintf("Carried the live points of %d of %d failed users into %s", carried, len(failed), version.Version))
+	return nil
+}
+
+// ListIndexVersions godoc
+//
+//	@Summary		List collection versions
+//	@Description	Complete versions of the users collections kept for rollback, oldest first. The live one is marked active. Platform operators only.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Success		200	{array}		qdrant.CollectionVersion
+//	@Failure		403	{object}	dto.UnauthorizedResponse	"Not a platform operator"
+//	@Failure		500	{object}	map[string]string			"Failed to list versions"
+//	@Router			/api/v1/reindex/versions [get]
+//	@Security		BearerAuth
+func (s *qdrantService) ListIndexVersions(c *gin.Context) {
+	versions, err := s.repo.ListVersions(c.Request.Context())
+	if err != nil {
+		lib.ResponseInternalServerError(c, err, "failed to list collection versions")
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+// ActivateIndexVersion godoc
+//
+//	@Summary		Roll back to a collection version
+//	@Description	Atomically switches search to a kept complete collection version. Affects every organization, platform operators only.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Param			version	path		string				true	"Collection version, e.g. base or v20250101120000"
+//	@Success		200		{object}	map[string]string	"Activated version"
+//	@Failure		403		{object}	dto.UnauthorizedResponse	"Not a platform operator"
+//	@Failure		409		{object}	map[string]string	"Reindex in progress or the version was built with another embedding model"
+//	@Failure		500		{object}	map[string]string	"Failed to switch version"
+//	@Router			/api/v1/reindex/versions/{version}/activate [post]
+//	@Security		BearerAuth
+func (s *qdrantService) ActivateIndexVersion(c *gin.Context) {
+	version := c.Param("version")
+	if err := s.repo.ActivateVersion(c.Request.Context(), version); err != nil {
+		if errors.Is(err, qdrant.ErrBuildInProgress) || errors.Is(err, qdrant.ErrModelMismatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		lib.ResponseInternalServerError(c, err, "failed to activate collection version")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "activated collection version " + version})
+}
+
+// GetEmbeddingModelStats godoc
+//
+//	@Summary		Count points per embedding model
+//	@Description	Live points of the organization by the model version that built them, next to the configured model. Mismatch is set while semantic search is disabled until a reindex.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Success		200	{object}	dto.EmbeddingModelStats
+//	@Failure		500	{object}	lib.ErrorResponse	"Failed to count points"
+//	@Router			/api/v1/reindex/models [get]
+//	@Security		BearerAuth
+func (s *qdrantService) GetEmbeddingModelStats(c *gin.Context) {
+	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
+	if !ok || userClaimsRaw == nil {
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
+		return
+	}
+	claims := userClaimsRaw.(*jwtUtils.UserClaims)
+
+	stats, err := s.repo.EmbeddingStats(c.Request.Context(), claims.OrganizationID.Hex())
+	if err != nil {
+		lib.ResponseInternalServerError(c, err, "Failed to count points per embedding model")
+		return
+	}
+
+	c.JSON(http.StatusOK, embeddingModelStatsDTO(stats))
+}
+
+// ConfirmEmbeddingModel godoc
+//
+//	@Summary		Confirm the model of unstamped points
+//	@Description	Points indexed before they were stamped, while the collection did not fit the configured dimensions, are stamped as unknown and keep semantic search disabled. Confirms they were built with the configured model. Affects every organization, platform operators only.
+//	@Tags			qdrant
+//	@Produce		json
+//	@Success		200	{object}	map[string]string			"Confirmed points"
+//	@Failure		403	{object}	dto.UnauthorizedResponse	"Not a platform operator"
+//	@Failure		409	{object}	map[string]string			"Reindex in progress or the collections have other dimensions"
+//	@Failure		500	{object}	map[string]string			"Failed to confirm points"
+//	@Router			/api/v1/reindex/models/confirm [post]
+//	@Security		BearerAuth
+func (s *qdrantService) ConfirmEmbeddingModel(c *gin.Context) {
+	confirmed, err := s.repo.ConfirmLegacyModel(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, qdrant.ErrBuildInProgress) || errors.Is(err, qdrant.ErrModelMismatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		lib.ResponseInternalServerError(c, err, "failed to confirm the embedding model")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("confirmed the embedding model of %d users", confirmed)})
+}
+
+func embeddingModelStatsDTO(stats qdrant.EmbeddingStats) dto.EmbeddingModelStats {
+	points := func(counts []qdrant.ModelPoints) []dto.EmbeddingModelPoints {
+		result := make([]dto.EmbeddingModelPoints, len(counts))
+		for i, count := range counts {
+			result[i] = dto.EmbeddingModelPoints{Model: count.Model, Version: count.Version, Points: count.Points}
+		}
+		return result
+	}
+
+	result := dto.EmbeddingModelStats{
+		Model:      stats.Model,
+		Version:    stats.Version,
+		Dimensions: stats.Dimensions,
+		Users:      points(stats.Users),
+		Passages:   points(stats.Passages),
+	}
+	if stats.Mismatch != nil {
+		result.Mismatch = stats.Mismatch.Error()
+	}
+	return result
+}
+
+// BackfillOrganizations adds organization_id to points indexed before tenant isolation, in the live version
+// and in the versions kept for rollback. Points of users that no longer exist in MongoDB are removed,
+// they can't be attributed to any organization
+func (s *qdrantService) BackfillOrganizations(ctx context.Context) (int, error) {
+	totalMigrated, err := s.backfillVersion(ctx, s.repo)
+	if err != nil {
+		return totalMigrated, err
+	}
+
+	versions, err := s.repo.ListVersions(ctx)
+	if err != nil {
+		return totalMigrated, err
+	}
+	for _, version := range versions {
+		if version.Active {
+			continue
+		}
+		migrated, err := s.backfillVersion(ctx, s.repo.InVersion(version))
+		totalMigrated += migrated
+		if err != nil {
+			return totalMigrated, fmt.Errorf("failed to backfill version %s: %w", version.Version, err)
+		}
+	}
+	return totalMigrated, nil
+}
+
+func (s *qdrantService) backfillVersion(ctx context.Context, repo qdrant.IQdrantRepository) (int, error) {
+	const batchSize = 100
+	totalMigrated := 0
+
+	for {
+		userIDs, err := repo.ScrollUsersWithoutOrganization(ctx, batchSize)
+		if err != nil {
+			return totalMigrated, err
+		}
+
+		if len(userIDs) == 0 {
+			break
+		}
+
+		oids := make([]primitive.ObjectID, 0, len(userIDs))
+		for _, id := range userIDs {
+			oid, err := mongoUtils.StringToObjectID(id)
+			if err != nil {
+				continue
+			}
+			oids = append(oids, oid)
+		}
+
+		users, err := s.userRepo.GetUsersByIDs(ctx, oids)
+		if err != nil {
+			return totalMigrated, err
+		}
+
+		userMap := make(map[string]*model.User, len(users))
+		for _, user := range users {
+			userMap[user.ID.Hex()] = user
+		}
+
+		for _, id := range userIDs {
+			user, ok := userMap[id]
+			if !ok || user.OrganizationID.IsZero() {
+				if err := repo.DeleteUser(ctx, id); err != nil {
+					return totalMigrated, err
+				}
+				continue
+			}
+
+			if err := repo.SetUserOrganization(ctx, id, user.OrganizationID.Hex()); err != nil {
+				return totalMigrated, err
+			}
+			totalMigrated++
+		}
+	}
+
+	return totalMigrated, nil
 }
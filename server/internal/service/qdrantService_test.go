@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/adapter/mongo"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/model"
+	"semki/internal/utils/config"
+	"testing"
+)
+
+func TestBlendGuidanceScoresRoutesBySeniority(t *testing.T) {
+	results := []qdrant.VectorSearchResult{
+		{UserID: "junior", Score: 0.80, Team: "eng", Level: "junior-level"},
+		{UserID: "senior", Score: 0.75, Team: "eng", Level: "senior-level"},
+	}
+	guidance := qdrant.GuidanceScores{
+		"eng":          0.6,
+		"junior-level": 0.1,
+		"senior-level": 0.9,
+	}
+
+	blended := blendGuidanceScores(results, guidance, config.SearchConfig{UserWeight: 0.6, TeamWeight: 0.2, LevelWeight: 0.2})
+
+	assert.Equal(t, "senior", blended[0].UserID)
+	assert.InDelta(t, 0.75*0.6+0.6*0.2+0.9*0.2, blended[0].Score, 1e-6)
+}
+
+func TestBlendGuidanceScoresWithoutGuidance(t *testing.T) {
+	results := []qdrant.VectorSearchResult{{UserID: "u", Score: 0.5}}
+
+	blended := blendGuidanceScores(results, qdrant.GuidanceScores{}, config.SearchConfig{UserWeight: 0.7, TeamWeight: 0.2, LevelWeight: 0.1})
+
+	assert.InDelta(t, 0.5, blended[0].Score, 1e-6)
+}
+
+// backfillRepo - one collection version, versions are its kept siblings
+type backfillRepo struct {
+	qdrant.IQdrantRepository
+	pending  []string
+	migrated map[string]string
+	versions map[string]*backfillRepo
+}
+
+func (r *backfillRepo) ScrollUsersWithoutOrganization(context.Context, uint32) ([]string, error) {
+	return r.pending, nil
+}
+
+func (r *backfillRepo) SetUserOrganization(_ context.Context, userID string, organizationID string) error {
+	r.migrated[userID] = organizationID
+	r.pending = nil
+	return nil
+}
+
+func (r *backfillRepo) ListVersions(context.Context) ([]qdrant.CollectionVersion, error) {
+	return []qdrant.CollectionVersion{{Version: "base"}, {Version: "v1", Active: true}}, nil
+}
+
+func (r *backfillRepo) InVersion(version qdrant.CollectionVersion) qdrant.IQdrantRepository {
+	return r.versions[version.Version]
+}
+
+type backfillUsers struct {
+	mongo.IUserRepository
+	user *model.User
+}
+
+func (r *backfillUsers) GetUsersByIDs(context.Context, []primitive.ObjectID) ([]*model.User, error) {
+	return []*model.User{r.user}, nil
+}
+
+func TestBackfillOrganizationsCoversKeptVersions(t *testing.T) {
+	user := &model.User{ID: primitive.NewObjectID(), OrganizationID: primitive.NewObjectID()}
+	base := &backfillRepo{pending: []string{user.ID.Hex()}, migrated: map[string]string{}}
+	live := &backfillRepo{migrated: map[string]string{}, versions: map[string]*backfillRepo{"base": base}}
+	s := &qdrantService{repo: live, userRepo: &backfillUsers{user: user}}
+
+	migrated, err := s.BackfillOrganizations(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+	assert.Equal(t, map[string]string{user.ID.Hex(): user.OrganizationID.Hex()}, base.migrated)
+}
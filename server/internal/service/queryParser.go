@@ -0,0 +1,100 @@
+package service
+
+import (
+	"regexp"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"sort"
+	"strings"
+)
+
+// namedFilter - team, level or location name with the pattern that finds it in a query
+type namedFilter struct {
+	value   dto.SearchFilterValue
+	pattern *regexp.Regexp
+}
+
+var extraSpaces = regexp.MustCompile(`\s+`)
+
+// ExtractQueryFilters turns "senior backend people in Berlin" into Levels=[Senior], Locations=[Berlin]
+// and the cleaned semantic query "backend people". Matching is rule-based against the organization names
+func ExtractQueryFilters(query string, semantic model.OrganizationSemantic) dto.ExtractedFilters {
+	teams := make([]namedFilter, 0, len(semantic.Teams))
+	for _, t := range semantic.Teams {
+		teams = append(teams, newNamedFilter(t.ID.Hex(), t.Name, false))
+	}
+
+	levels := make([]namedFilter, 0, len(semantic.Levels))
+	for _, l := range semantic.Levels {
+		levels = append(levels, newNamedFilter(l.ID.Hex(), l.Name, false))
+	}
+
+	locations := make([]namedFilter, 0, len(semantic.Locations))
+	for _, loc := range semantic.Locations {
+		locations = append(locations, newNamedFilter(loc.ID.Hex(), loc.Name, true))
+	}
+
+	result := dto.ExtractedFilters{}
+	cleaned := query
+	cleaned, result.Locations = matchNamedFilters(cleaned, locations)
+	cleaned, result.Teams = matchNamedFilters(cleaned, teams)
+	cleaned, result.Levels = matchNamedFilters(cleaned, levels)
+
+	cleaned = strings.Trim(extraSpaces.ReplaceAllString(cleaned, " "), " ,.;")
+	if cleaned == "" {
+		// Query consisted only of filters, keep the original text for the embedding
+		cleaned = query
+	}
+	result.Query = cleaned
+
+	return result
+}
+
+func newNamedFilter(id, name string, withPreposition bool) namedFilter {
+	prefix := ""
+	if withPreposition {
+		prefix = `(?:(?:based\s+)?(?:in|from|at)\s+)?`
+	}
+	return namedFilter{
+		value:   dto.SearchFilterValue{ID: id, Name: name},
+		pattern: regexp.MustCompile(`(?i)\b` + prefix + regexp.QuoteMeta(name) + `s?\b`),
+	}
+}
+
+// matchNamedFilters removes every matched name from the query. Longer names go first,
+// so "Senior Staff" wins over "Senior"
+func matchNamedFilters(query string, filters []namedFilter) (string, []dto.SearchFilterValue) {
+	sort.SliceStable(filters, func(i, j int) bool {
+		return len(filters[i].value.Name) > len(filters[j].value.Name)
+	})
+
+	matched := make([]dto.SearchFilterValue, 0)
+	for _, f := range filters {
+		if f.value.Name == "" || !f.pattern.MatchString(query) {
+			continue
+		}
+		query = f.pattern.ReplaceAllString(query, " ")
+		matched = append(matched, f.value)
+	}
+
+	return query, matched
+}
+
+// filterIDs - ids of extracted values merged with explicitly requested ones
+func filterIDs(explicit []string, extracted []dto.SearchFilterValue) []string {
+	seen := make(map[string]struct{}, len(explicit)+len(extracted))
+	ids := make([]string, 0, len(explicit)+len(extracted))
+	for _, id := range explicit {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	for _, v := range extracted {
+		if _, ok := seen[v.ID]; !ok {
+			seen[v.ID] = struct{}{}
+			ids = append(ids, v.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/model"
+	"testing"
+)
+
+func TestExtractQueryFilters(t *testing.T) {
+	semantic := model.OrganizationSemantic{
+		Levels:    []model.Level{{ID: primitive.NewObjectID(), Name: "Senior"}, {ID: primitive.NewObjectID(), Name: "Junior"}},
+		Teams:     []model.Team{{ID: primitive.NewObjectID(), Name: "Design"}},
+		Locations: []model.Location{{ID: primitive.NewObjectID(), Name: "Berlin"}},
+	}
+
+	filters := ExtractQueryFilters("senior backend people in Berlin", semantic)
+
+	assert.Equal(t, "backend people", filters.Query)
+	assert.Len(t, filters.Levels, 1)
+	assert.Equal(t, "Senior", filters.Levels[0].Name)
+	assert.Len(t, filters.Locations, 1)
+	assert.Equal(t, semantic.Locations[0].ID.Hex(), filters.Locations[0].ID)
+	assert.Empty(t, filters.Teams)
+}
+
+func TestExtractQueryFiltersKeepsQueryWithoutMatches(t *testing.T) {
+	semantic := model.OrganizationSemantic{
+		Teams: []model.Team{{ID: primitive.NewObjectID(), Name: "Design"}},
+	}
+
+	filters := ExtractQueryFilters("who knows kubernetes?", semantic)
+	assert.True(t, filters.IsEmpty())
+	assert.Equal(t, "who knows kubernetes?", filters.Query)
+
+	filters = ExtractQueryFilters("design", semantic)
+	assert.Equal(t, "design", filters.Query)
+	assert.Len(t, filters.Teams, 1)
+}
+
+func TestFilterIDsMergesWithoutDuplicates(t *testing.T) {
+	extracted := ExtractQueryFilters("Design", model.OrganizationSemantic{
+		Teams: []model.Team{{ID: primitive.NewObjectID(), Name: "Design"}},
+	})
+	explicit := []string{extracted.Teams[0].ID, "other"}
+	assert.Equal(t, explicit, filterIDs(explicit, extracted.Teams))
+}
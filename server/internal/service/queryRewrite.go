@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"strings"
+)
+
+// maxHistoryResults - older suggestions add tokens without helping the rewrite
+const maxHistoryResults = 10
+
+// chatHistory renders previous queries with their filters and the people suggested so far
+func chatHistory(chat *model.Chat, org model.Organization, shownUsers []*model.User) string {
+	teams, levels, locations := organizationFilterValues(org.Semantic)
+
+	var history strings.Builder
+	for _, message := range chat.Messages {
+		if message.Role != "user" {
+			continue
+		}
+		query, _ := message.Content["rewritten_query"].(string)
+		if query == "" {
+			query, _ = message.Content["title"].(string)
+		}
+		fmt.Fprintf(&history, "User searched: %s", query)
+
+		var filters []string
+		filters = append(filters, filterNames(contentStrings(message.Content["teams"]), teams)...)
+		filters = append(filters, filterNames(contentStrings(message.Content["levels"]), levels)...)
+		filters = append(filters, filterNames(contentStrings(message.Content["locations"]), locations)...)
+		if len(filters) > 0 {
+			fmt.Fprintf(&history, " (filters: %s)", strings.Join(filters, ", "))
+		}
+		history.WriteString("\n")
+	}
+
+	if len(shownUsers) > 0 {
+		history.WriteString("Suggested people:\n")
+		for _, user := range shownUsers {
+			teamName, levelName, locationName := semanticNames(org, *user)
+			fmt.Fprintf(&history, "- %s: Team %s, Level %s, Location %s\n", user.Name, teamName, levelName, locationName)
+		}
+	}
+
+	return history.String()
+}
+
+// resolveQueryRewrite maps names of the rewrite back to organization ids, unknown names are dropped
+func resolveQueryRewrite(message string, rewrite QueryRewrite, semantic model.OrganizationSemantic) dto.ChatRefinement {
+	teams, levels, locations := organizationFilterValues(semantic)
+	return dto.ChatRefinement{
+		Message:   message,
+		Query:     strings.TrimSpace(rewrite.Query),
+		Teams:     filterValuesByName(rewrite.Teams, teams),
+		Levels:    filterValuesByName(rewrite.Levels, levels),
+		Locations: filterValuesByName(rewrite.Locations, locations),
+	}
+}
+
+func organizationFilterValues(semantic model.OrganizationSemantic) (teams, levels, locations []dto.SearchFilterValue) {
+	for _, t := range semantic.Teams {
+		teams = append(teams, dto.SearchFilterValue{ID: t.ID.Hex(), Name: t.Name})
+	}
+	for _, l := range semantic.Levels {
+		levels = append(levels, dto.SearchFilterValue{ID: l.ID.Hex(), Name: l.Name})
+	}
+	for _, loc := range semantic.Locations {
+		locations = append(locations, dto.SearchFilterValue{ID: loc.ID.Hex(), Name: loc.Name})
+	}
+	return teams, levels, locations
+}
+
+func filterValuesByName(names []string, values []dto.SearchFilterValue) []dto.SearchFilterValue {
+	matched := make([]dto.SearchFilterValue, 0, len(names))
+	for _, name := range names {
+		for _, v := range values {
+			if strings.EqualFold(strings.TrimSpace(name), v.Name) {
+				matched = append(matched, v)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func filterNames(ids []string, values []dto.SearchFilterValue) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		for _, v := range values {
+			if v.ID == id {
+				names = append(names, v.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// contentStrings reads a string list of message content, decoded from Mongo as primitive.A
+func contentStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case primitive.A:
+		return contentStrings([]interface{}(v))
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+func lastN(items []string, n int) []string {
+	if len(items) > n {
+		return items[len(items)-n:]
+	}
+	return items
+}
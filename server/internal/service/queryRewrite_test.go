@@ -0,0 +1,44 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/model"
+	"testing"
+)
+
+func TestResolveQueryRewrite(t *testing.T) {
+	london := model.Location{ID: primitive.NewObjectID(), Name: "London"}
+	senior := model.Level{ID: primitive.NewObjectID(), Name: "Senior"}
+	semantic := model.OrganizationSemantic{
+		Levels:    []model.Level{senior},
+		Locations: []model.Location{london},
+	}
+
+	refinement := resolveQueryRewrite("same but in London", QueryRewrite{
+		Query:     " kafka engineers ",
+		Levels:    []string{"senior"},
+		Locations: []string{"London", "Atlantis"},
+	}, semantic)
+
+	assert.Equal(t, "kafka engineers", refinement.Query)
+	assert.Equal(t, "same but in London", refinement.Message)
+	assert.Equal(t, senior.ID.Hex(), refinement.Levels[0].ID)
+	assert.Len(t, refinement.Locations, 1)
+	assert.Equal(t, london.ID.Hex(), refinement.Locations[0].ID)
+}
+
+func TestChatHistory(t *testing.T) {
+	berlin := model.Location{ID: primitive.NewObjectID(), Name: "Berlin"}
+	org := model.Organization{Semantic: model.OrganizationSemantic{Locations: []model.Location{berlin}}}
+	chat := &model.Chat{Messages: []model.Message{
+		{Role: "user", Content: bson.M{"title": "kafka", "locations": primitive.A{berlin.ID.Hex()}}},
+		{Role: "user", Content: bson.M{"title": "more senior", "rewritten_query": "senior kafka"}},
+	}}
+
+	history := chatHistory(chat, org, nil)
+
+	assert.Contains(t, history, "User searched: kafka (filters: Berlin)")
+	assert.Contains(t, history, "User searched: senior kafka\n")
+}
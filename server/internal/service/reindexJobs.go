@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"semki/internal/controller/http/v1/dto"
+	"sync"
+	"time"
+)
+
+// ErrReindexRunning - a second reindex of the same organization would race the first one on the same points
+var ErrReindexRunning = errors.New("a reindex job is already running for the organization")
+
+// ErrReindexIncomplete - committing the version would drop the users that failed from search
+var ErrReindexIncomplete = errors.New("some users failed to reindex, the live version is kept")
+
+// reindexJobRetention - finished jobs stay queryable for this long
+const reindexJobRetention = 24 * time.Hour
+
+// maxReindexFailures - failure reasons kept per job, the counter keeps counting past it
+const maxReindexFailures = 100
+
+// buildWaitInterval - how often a queued job retries to start its collection version
+const buildWaitInterval = 2 * time.Second
+
+type reindexJob struct {
+	mu     sync.Mutex
+	state  dto.ReindexJob
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (j *reindexJob) snapshot() dto.ReindexJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	state := j.state
+	state.Failures = append([]dto.ReindexFailure{}, j.state.Failures...)
+	return state
+}
+
+func (j *reindexJob) update(fn func(state *dto.ReindexJob)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.state)
+}
+
+func (j *reindexJob) processed(userID string, err error) {
+	j.update(func(state *dto.ReindexJob) {
+		state.Processed++
+		if err == nil {
+			return
+		}
+		state.Failed++
+		if len(state.Failures) < maxReindexFailures {
+			state.Failures = append(state.Failures, dto.ReindexFailure{UserID: userID, Reason: err.Error()})
+		}
+	})
+}
+
+// finish records the outcome, a cancelled context wins over the error it caused
+func (j *reindexJob) finish(ctx context.Context, err error) {
+	j.update(func(state *dto.ReindexJob) {
+		now := time.Now()
+		state.FinishedAt = &now
+		switch {
+		case err != nil && ctx.Err() != nil:
+			state.Status = dto.ReindexJobStatuses.Cancelled
+		case err != nil:
+			state.Status = dto.ReindexJobStatuses.Failed
+			state.Error = err.Error()
+		default:
+			state.Status = dto.ReindexJobStatuses.Completed
+		}
+	})
+	close(j.done)
+}
+
+// reindexJobs keeps jobs in memory, one running job per organization
+type reindexJobs struct {
+	mu      sync.Mutex
+	jobs    map[string]*reindexJob
+	running map[string]*reindexJob
+}
+
+func newReindexJobs() *reindexJobs {
+	return &reindexJobs{
+		jobs:    make(map[string]*reindexJob),
+		running: make(map[string]*reindexJob),
+	}
+}
+
+// start registers a queued job, the returned context is cancelled by cancel
+func (r *reindexJobs) start(parent context.Context, organizationID string) (*reindexJob, context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if running, ok := r.running[organizationID]; ok {
+		return running, nil, ErrReindexRunning
+	}
+	r.prune()
+
+	ctx, cancel := context.WithCancel(parent)
+	job := &reindexJob{
+		state: dto.ReindexJob{
+			ID:             primitive.NewObjectID().Hex(),
+			OrganizationID: organizationID,
+			Status:         dto.ReindexJobStatuses.Queued,
+			Failures:       []dto.ReindexFailure{},
+			StartedAt:      time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	r.jobs[job.state.ID] = job
+	r.running[organizationID] = job
+	return job, ctx, nil
+}
+
+// get returns the job only to its organization
+func (r *reindexJobs) get(id string, organizationID string) (*reindexJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[id]
+	if !ok || job.state.OrganizationID != organizationID {
+		return nil, false
+	}
+	return job, true
+}
+
+func (r *reindexJobs) release(job *reindexJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.cancel()
+	if r.running[job.state.OrganizationID] == job {
+		delete(r.running, job.state.OrganizationID)
+	}
+}
+
+// prune drops finished jobs past the retention, callers hold mu
+func (r *reindexJobs) prune() {
+	for id, job := range r.jobs {
+		state := job.snapshot()
+		if state.FinishedAt != nil && time.Since(*state.FinishedAt) > reindexJobRetention {
+			delete(r.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"semki/internal/adapter/mongo"
+	"semki/internal/adapter/qdrant"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"semki/pkg/telemetry"
+	"testing"
+)
+
+func TestReindexJobsOnePerOrganization(t *testing.T) {
+	jobs := newReindexJobs()
+
+	job, ctx, err := jobs.start(context.Background(), "org")
+	assert.NoError(t, err)
+	assert.Equal(t, dto.ReindexJobStatuses.Queued, job.snapshot().Status)
+
+	running, _, err := jobs.start(context.Background(), "org")
+	assert.ErrorIs(t, err, ErrReindexRunning)
+	assert.Equal(t, job.snapshot().ID, running.snapshot().ID)
+
+	_, _, err = jobs.start(context.Background(), "other")
+	assert.NoError(t, err)
+
+	_, ok := jobs.get(job.snapshot().ID, "other")
+	assert.False(t, ok)
+
+	job.finish(ctx, nil)
+	jobs.release(job)
+	_, _, err = jobs.start(context.Background(), "org")
+	assert.NoError(t, err)
+}
+
+func TestReindexJobProgress(t *testing.T) {
+	jobs := newReindexJobs()
+	job, ctx, _ := jobs.start(context.Background(), "org")
+
+	job.processed("a", nil)
+	job.processed("b", errors.New("embedding failed"))
+
+	job.cancel()
+	job.finish(ctx, ctx.Err())
+
+	state := job.snapshot()
+	assert.Equal(t, 2, state.Processed)
+	assert.Equal(t, 1, state.Failed)
+	assert.Equal(t, []dto.ReindexFailure{{UserID: "b", Reason: "embedding failed"}}, state.Failures)
+	assert.Equal(t, dto.ReindexJobStatuses.Cancelled, state.Status)
+	assert.NotNil(t, state.FinishedAt)
+}
+
+// buildRepo records the users carried over from the live version
+type buildRepo struct {
+	qdrant.IQdrantRepository
+	carried []string
+}
+
+func (r *buildRepo) CopyPoints(context.Context, qdrant.CollectionVersion, string) (int, error) {
+	return 0, nil
+}
+
+func (r *buildRepo) InVersion(qdrant.CollectionVersion) qdrant.IQdrantRepository {
+	return r
+}
+
+func (r *buildRepo) CopyUsers(_ context.Context, _ qdrant.CollectionVersion, userIDs []string) (int, error) {
+	r.carried = append(r.carried, userIDs...)
+	return len(userIDs), nil
+}
+
+type buildUsers struct {
+	mongo.IUserRepository
+	users []*model.User
+}
+
+func (r *buildUsers) GetUsersByOrganization(context.Context, primitive.ObjectID, string, int, int) ([]*model.User, int64, error) {
+	return r.users, int64(len(r.users)), nil
+}
+
+func TestBuildVersionKeepsLiveVersionOnFailures(t *testing.T) {
+	telemetry.Log = zap.NewNop()
+	user := &model.User{ID: primitive.NewObjectID()}
+	repo := &buildRepo{}
+	// Zero dimensions, every embedding fails
+	s := &qdrantService{repo: repo, userRepo: &buildUsers{users: []*model.User{user}}, embedder: NewHashingEmbedder(0)}
+
+	job, ctx, _ := newReindexJobs().start(context.Background(), "org")
+	err := s.buildVersion(ctx, job, qdrant.CollectionVersion{}, primitive.NewObjectID())
+	assert.ErrorIs(t, err, ErrReindexIncomplete)
+	assert.Empty(t, repo.carried)
+
+	job.update(func(state *dto.ReindexJob) { state.AcceptFailures = true })
+	assert.NoError(t, s.buildVersion(ctx, job, qdrant.CollectionVersion{}, primitive.NewObjectID()))
+	assert.Equal(t, []string{user.ID.Hex()}, repo.carried, "failed users keep their live points")
+}
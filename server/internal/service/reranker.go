@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"sort"
+)
+
+//region RerankStrategy
+
+type RerankStrategy string
+
+var RerankStrategies = struct {
+	None         RerankStrategy
+	CrossEncoder RerankStrategy
+	LLM          RerankStrategy
+}{
+	None:         "none",
+	CrossEncoder: "cross_encoder",
+	LLM:          "llm",
+}
+
+// ParseRerankStrategy returns fallback for an empty value, the fallback is checked too
+func ParseRerankStrategy(strategy string, fallback RerankStrategy) (RerankStrategy, error) {
+	if strategy == "" {
+		strategy = string(fallback)
+	}
+	switch RerankStrategy(strategy) {
+	case RerankStrategies.None, RerankStrategies.CrossEncoder, RerankStrategies.LLM:
+		return RerankStrategy(strategy), nil
+	default:
+		return "", fmt.Errorf("unknown rerank strategy %q", strategy)
+	}
+}
+
+//endregion
+
+// rerankResults scores hydrated candidates with the chosen strategy and orders them by the new score
+func (s *searchService) rerankResults(
+	ctx context.Context,
+	strategy RerankStrategy,
+	query string,
+	org model.Organization,
+	results []dto.SearchResultWithUser,
+) ([]dto.SearchResultWithUser, error) {
+	if strategy == RerankStrategies.None || len(results) == 0 {
+		return results, nil
+	}
+
+	var scores []float32
+	var err error
+	switch strategy {
+	case RerankStrategies.CrossEncoder:
+		texts := make([]string, len(results))
+		for i, res := range results {
+			texts[i] = res.User.Semantic.Description
+		}
+		scores, err = s.embedder.Rerank(ctx, query, texts)
+	case RerankStrategies.LLM:
+		users := make([]model.User, len(results))
+		for i, res := range results {
+			users[i] = *res.User
+		}
+		scores, err = s.llm.RerankUsers(ctx, query, org, users)
+	default:
+		err = fmt.Errorf("unknown rerank strategy %q", strategy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return applyRerankScores(results, scores)
+}
+
+// applyRerankScores keeps the vector score and orders by the rerank score, ties keep vector order
+func applyRerankScores(results []dto.SearchResultWithUser, scores []float32) ([]dto.SearchResultWithUser, error) {
+	if len(scores) != len(results) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d results", len(scores), len(results))
+	}
+
+	reranked := make([]dto.SearchResultWithUser, len(results))
+	copy(reranked, results)
+	for i := range reranked {
+		score := scores[i]
+		reranked[i].RerankScore = &score
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return *reranked[i].RerankScore > *reranked[j].RerankScore
+	})
+	return reranked, nil
+}
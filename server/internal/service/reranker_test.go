@@ -0,0 +1,53 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"testing"
+)
+
+func TestApplyRerankScores(t *testing.T) {
+	a, b, c := &model.User{Name: "a"}, &model.User{Name: "b"}, &model.User{Name: "c"}
+	results := []dto.SearchResultWithUser{
+		{Score: 0.9, User: a},
+		{Score: 0.8, User: b},
+		{Score: 0.7, User: c},
+	}
+
+	reranked, err := applyRerankScores(results, []float32{0.1, 0.9, 0.1})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*model.User{b, a, c}, []*model.User{reranked[0].User, reranked[1].User, reranked[2].User})
+	assert.Equal(t, float32(0.8), reranked[0].Score)
+	assert.Equal(t, float32(0.9), *reranked[0].RerankScore)
+	assert.Nil(t, results[0].RerankScore)
+
+	_, err = applyRerankScores(results, []float32{0.1})
+	assert.Error(t, err, "missing scores")
+}
+
+func TestParseRerankStrategy(t *testing.T) {
+	strategy, err := ParseRerankStrategy("", RerankStrategies.LLM)
+	assert.NoError(t, err)
+	assert.Equal(t, RerankStrategies.LLM, strategy)
+
+	strategy, err = ParseRerankStrategy("cross_encoder", RerankStrategies.None)
+	assert.NoError(t, err)
+	assert.Equal(t, RerankStrategies.CrossEncoder, strategy)
+
+	_, err = ParseRerankStrategy("magic", RerankStrategies.None)
+	assert.Error(t, err)
+
+	_, err = ParseRerankStrategy("", "magic")
+	assert.Error(t, err, "an invalid fallback is not returned")
+}
+
+func TestParseRerankScores(t *testing.T) {
+	scores, err := parseRerankScores(`{"scores":[{"id":1,"score":0.7},{"id":5,"score":1}]}`, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []float32{0, 0.7}, scores)
+
+	_, err = parseRerankScores("not json", 2)
+	assert.Error(t, err)
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sashabaranov/go-openai"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
@@ -14,6 +16,7 @@ import (
 	"semki/internal/controller/http/v1/dto"
 	"semki/internal/controller/http/v1/routes"
 	"semki/internal/model"
+	"semki/internal/utils/config"
 	"semki/internal/utils/jwtUtils"
 	"semki/internal/utils/mongoUtils"
 	"semki/pkg/lib"
@@ -23,25 +26,32 @@ import (
 	"time"
 )
 
+// maxSearchOffset - deeper pages are not worth the over-fetch, refine the query instead
+const maxSearchOffset = 100
+
 type searchService struct {
 	qdrantService IQdrantService
 	llm           ILLMService
+	embedder      IEmbedderService
 	orgRepo       mongo.IOrganizationRepository
 	chatRepo      mongo.IChatRepository
 	userRepo      mongo.IUserRepository
 	logger        *zap.Logger
+	searchCfg     config.SearchConfig
 }
 
 // NewSearchService creates a new search service
 func NewSearchService(
 	vectorDB IQdrantService,
 	llm ILLMService,
+	embedder IEmbedderService,
 	orgRepo mongo.IOrganizationRepository,
 	chatRepo mongo.IChatRepository,
 	userRepo mongo.IUserRepository,
 	logger *zap.Logger,
+	searchCfg config.SearchConfig,
 ) routes.ISearchService {
-	return &searchService{vectorDB, llm, orgRepo, chatRepo, userRepo, logger}
+	return &searchService{vectorDB, llm, embedder, orgRepo, chatRepo, userRepo, logger, searchCfg}
 }
 
 // Search godoc
@@ -49,6 +59,10 @@ func NewSearchService(
 //	@Summary		Semantic user search
 //	@Description	Performs a semantic search for users using text embeddings and optional filters.
 //						Results are streamed one by one with optional AI-generated descriptions.
+//						Filters recognized in the query are streamed first as a 'filters' event,
+//						then candidate counts per team, level and location as a 'facets' event.
+//						Without page/cursor every call continues the chat: users already shown in it are excluded ("show more").
+//						With page/cursor the ranking is paged by offset and a 'page' event with the next cursor ends the stream.
 //	@Tags			chats
 //	@Security		BearerAuth
 //	@Accept			json
@@ -59,10 +73,27 @@ func NewSearchService(
 //	@Param			levels		query		[]string								false	"Filter users by experience levels (can be multiple)"
 //	@Param			locations	query		[]string								false	"Filter users by locations (can be multiple)"
 //	@Param			limit		query		int										false	"Maximum number of users to return (default 5, max 20)"
-//	@Success		200			{object}	dto.SearchResultWithUserAndDescription	"Streamed search results with semantic descriptions"
+//	@Param			mode		query		string									false	"Ranking mode: semantic (default), lexical or hybrid"	Enums(semantic, lexical, hybrid)
+//	@Param			extract		query		bool									false	"Extract teams, levels and locations from the query text (default true). Sent as a 'filters' event"
+//	@Param			excludeTeams		query		[]string						false	"Exclude users of these teams"
+//	@Param			excludeLevels		query		[]string						false	"Exclude users of these levels"
+//	@Param			excludeLocations	query		[]string						false	"Exclude users of these locations"
+//	@Param			excludeUsers		query		[]string						false	"Exclude these user IDs, e.g. people already contacted"
+//	@Param			not			query		string									false	"Negative text the results should be far from, e.g. 'frontend'. Ignored in lexical mode"
+//	@Param			page		query		int										false	"1-based page of the ranking, disables excluding users already shown in the chat"
+//	@Param			cursor		query		string									false	"Opaque cursor from the previous 'page' event, takes precedence over page"
+//	@Param			diversity	query		number									false	"MMR lambda in (0, 1], lower values diversify more (default from server config, 0 disables)"
+//	@Param			maxPerTeam	query		int										false	"Maximum number of results from the same team"
+//	@Param			maxPerLocation	query	int										false	"Maximum number of results from the same location"
+//	@Param			facets		query		bool									false	"Send candidate counts per team, level and location as a 'facets' event (default true)"
+//	@Param			aggregate	query		string									false	"Rank by description passages (default from server config)"	Enums(none, max, sum)
+//	@Param			rerank		query		string									false	"Rerank candidates before the final cut (default from server config)"	Enums(none, cross_encoder, llm)
+//	@Success		200			{object}	dto.SearchResultWithUser	"Streamed search results, their descriptions follow as description_delta and description_done events"
 //	@Failure		400			{object}	map[string]string						"Invalid query parameters"
 //	@Failure		401			{object}	dto.UnauthorizedResponse				"Unauthorized"
 //	@Failure		500			{object}	map[string]string						"Internal server error during search or embedding"
+//	@Failure		503			{object}	map[string]string						"Embedder unavailable or the index was built with another embedding model"
+//	@Failure		504			{object}	map[string]string						"Embedder timed out"
 //	@Router			/api/v1/search [get]
 func (s *searchService) Search(c *gin.Context) {
 	var req dto.SearchRequest
@@ -72,12 +103,6 @@ func (s *searchService) Search(c *gin.Context) {
 		return
 	}
 
-	if req.Limit == 0 {
-		req.Limit = 5
-	} else if req.Limit > 20 {
-		req.Limit = 20
-	}
-
 	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
 	if !ok || userClaimsRaw == nil {
 		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
@@ -103,163 +128,722 @@ func (s *searchService) Search(c *gin.Context) {
 		return
 	}
 
+	organization, err := s.orgRepo.GetOrganizationByID(ctx, claims.OrganizationID)
+	if err != nil {
+		s.logger.Error("Failed to get organization: " + err.Error())
+		lib.ResponseInternalServerError(c, err, "Failed to get organization")
+		return
+	} else if organization == nil {
+		lib.ResponseNotFound(c, "Organization not found")
+		return
+	}
+
+	s.streamSearch(c, claims, chat, organization, req, nil)
+}
+
+// ChatMessage godoc
+//
+//	@Summary		Follow-up search in a chat
+//	@Description	Rewrites a follow-up like "someone more senior" or "same but in London" into a standalone query
+//						using the chat history, stores it and streams the search like /search.
+//						The rewritten query and filters are streamed first as a 'refinement' event.
+//	@Tags			chats
+//	@Security		BearerAuth
+//	@Accept			json
+//	@Produce		text/event-stream
+//	@Param			id		path		string									true	"Chat ID"
+//	@Param			request	body		dto.ChatMessageRequest					true	"Follow-up message"
+//	@Success		200		{object}	dto.SearchResultWithUser	"Streamed search results, their descriptions follow as description_delta and description_done events"
+//	@Failure		400		{object}	map[string]string						"Invalid request"
+//	@Failure		401		{object}	dto.UnauthorizedResponse				"Unauthorized"
+//	@Failure		404		{object}	map[string]string						"Chat not found"
+//	@Failure		500		{object}	map[string]string						"Internal server error"
+//	@Failure		503		{object}	map[string]string						"Embedder unavailable or the index was built with another embedding model"
+//	@Failure		504		{object}	map[string]string						"Embedder timed out"
+//	@Router			/api/v1/chat/{id}/message [post]
+func (s *searchService) ChatMessage(c *gin.Context) {
+	var body dto.ChatMessageRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		lib.ResponseBadRequest(c, err, "invalid request body")
+		return
+	}
+
+	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
+	if !ok || userClaimsRaw == nil {
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
+		return
+	}
+	claims := userClaimsRaw.(*jwtUtils.UserClaims)
+
+	ctx := c.Request.Context()
+
+	chatObjID, err := mongoUtils.StringToObjectID(c.Param("id"))
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "invalid chat id")
+		return
+	}
+
+	chat, err := s.chatRepo.GetChatByID(ctx, chatObjID, claims.ID)
+	if err != nil {
+		lib.ResponseInternalServerError(c, err, "failed to fetch chat")
+		return
+	} else if chat == nil {
+		lib.ResponseNotFound(c, "chat does not exist")
+		return
+	}
+
+	organization, err := s.orgRepo.GetOrganizationByID(ctx, claims.OrganizationID)
+	if err != nil {
+		s.logger.Error("Failed to get organization: " + err.Error())
+		lib.ResponseInternalServerError(c, err, "Failed to get organization")
+		return
+	} else if organization == nil {
+		lib.ResponseNotFound(c, "Organization not found")
+		return
+	}
+
+	shownIDs, err := parseObjectIDs(lastN(chatUserIDs(chat), maxHistoryResults))
+	if err != nil {
+		s.logger.Warn("Chat contains invalid user id: " + err.Error())
+		shownIDs = nil
+	}
+	shownUsers, err := s.userRepo.GetUsersByIDs(ctx, shownIDs)
+	if err != nil {
+		lib.ResponseInternalServerError(c, err, "Failed to get chat users")
+		return
+	}
+
+	refinement := s.refineQuery(ctx, *organization, chat, shownUsers, body.Message)
+
+	message := model.Message{
+		Role: openai.ChatMessageRoleUser,
+		Content: bson.M{
+			"title":           body.Message,
+			"rewritten_query": refinement.Query,
+			"teams":           filterIDs(nil, refinement.Teams),
+			"levels":          filterIDs(nil, refinement.Levels),
+			"locations":       filterIDs(nil, refinement.Locations),
+			"limit":           body.Limit,
+		},
+		Timestamp: time.Now(),
+	}
+	if err := s.chatRepo.AddChatMessages(ctx, chatObjID, []model.Message{message}); err != nil {
+		lib.ResponseInternalServerError(c, err, "Failed to save chat message")
+		return
+	}
+
+	req := dto.SearchRequest{
+		ChatId:    chatObjID.Hex(),
+		Query:     refinement.Query,
+		Teams:     filterIDs(nil, refinement.Teams),
+		Levels:    filterIDs(nil, refinement.Levels),
+		Locations: filterIDs(nil, refinement.Locations),
+		Limit:     body.Limit,
+		Mode:      body.Mode,
+		Rerank:    body.Rerank,
+	}
+	s.streamSearch(c, claims, chat, organization, req, &refinement)
+}
+
+// refineQuery rewrites the follow-up into a standalone query. When the LLM fails the message is searched as is
+func (s *searchService) refineQuery(
+	ctx context.Context,
+	org model.Organization,
+	chat *model.Chat,
+	shownUsers []*model.User,
+	message string,
+) dto.ChatRefinement {
+	rewrite, err := s.llm.RewriteQuery(ctx, org, chatHistory(chat, org, shownUsers), message)
+	if err != nil || strings.TrimSpace(rewrite.Query) == "" {
+		if err != nil {
+			s.logger.Warn("RewriteQuery failed, searching the message as is: " + err.Error())
+		}
+		return dto.ChatRefinement{Message: message, Query: message}
+	}
+
+	return resolveQueryRewrite(message, rewrite, org.Semantic)
+}
+
+// streamSearch runs the search of req inside the chat and streams results, refinement goes first when present
+func (s *searchService) streamSearch(
+	c *gin.Context,
+	claims *jwtUtils.UserClaims,
+	chat *model.Chat,
+	organization *model.Organization,
+	req dto.SearchRequest,
+	refinement *dto.ChatRefinement,
+) {
+	ctx := c.Request.Context()
+	userID := claims.ID
+	chatObjID := chat.ID
+
+	if req.Limit == 0 {
+		req.Limit = 5
+	} else if req.Limit > 20 {
+		req.Limit = 20
+	}
+
+	mode, err := qdrant.ParseSearchMode(req.Mode)
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Invalid search mode")
+		return
+	}
+
+	rerank, err := ParseRerankStrategy(req.Rerank, RerankStrategy(s.searchCfg.Rerank))
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Invalid rerank strategy")
+		return
+	}
+
+	aggregate := req.Aggregate
+	if aggregate == "" {
+		aggregate = s.searchCfg.PassageAggregation
+	}
+	passages, err := qdrant.ParsePassageAggregation(aggregate)
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Invalid passage aggregation")
+		return
+	}
+
 	filters := qdrant.SearchFilters{
-		Query:     req.Query,
-		Teams:     req.Teams,
-		Levels:    req.Levels,
-		Locations: req.Locations,
-		Limit:     req.Limit,
+		OrganizationID: claims.OrganizationID.Hex(),
+		Query:          req.Query,
+		Mode:           mode,
+		Teams:          req.Teams,
+		Levels:         req.Levels,
+		Locations:      req.Locations,
+		Limit:          req.Limit,
+
+		ExcludeTeams:     req.ExcludeTeams,
+		ExcludeLevels:    req.ExcludeLevels,
+		ExcludeLocations: req.ExcludeLocations,
+		ExcludeUserIDs:   req.ExcludeUsers,
+		NegativeQuery:    req.NegativeQuery,
+
+		Passages: passages,
+
+		Diversity: qdrant.Diversity{
+			Lambda:         s.searchCfg.DiversityLambda,
+			MaxPerTeam:     req.MaxPerTeam,
+			MaxPerLocation: req.MaxPerLocation,
+		},
+	}
+	if req.Diversity != nil {
+		filters.Diversity.Lambda = *req.Diversity
+	}
+
+	offset, err := searchOffset(req)
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Invalid page or cursor")
+		return
+	}
+	paged := req.Page > 0 || req.Cursor != ""
+
+	shown := chatUserIDs(chat)
+	if !paged {
+		filters.ExcludeUserIDs = append(filters.ExcludeUserIDs, shown...)
+	}
+	filters.Limit = offset + req.Limit
+
+	var extracted dto.ExtractedFilters
+	if req.ExtractFilters {
+		extracted = ExtractQueryFilters(req.Query, organization.Semantic)
+		filters.Query = extracted.Query
+		filters.Teams = filterIDs(req.Teams, extracted.Teams)
+		filters.Levels = filterIDs(req.Levels, extracted.Levels)
+		filters.Locations = filterIDs(req.Locations, extracted.Locations)
+	}
+
+	// Reranking looks at a wider candidate pool and cuts it back to the requested limit
+	if rerank != RerankStrategies.None && uint64(s.searchCfg.RerankCandidates) > filters.Limit {
+		filters.Limit = uint64(s.searchCfg.RerankCandidates)
 	}
 
-	vectorSearchResults, err := s.qdrantService.SearchUsers(ctx, filters)
+	vectorSearchResults, queryVector, err := s.qdrantService.SearchUsers(ctx, filters)
 	if err != nil {
 		s.logger.Error("Search failed: " + err.Error())
-		lib.ResponseInternalServerError(c, err, "Search failed vector DB")
+		responseEmbedderError(c, err, "Search failed vector DB")
 		return
 	}
 
-	userIDs := make([]primitive.ObjectID, 0, len(vectorSearchResults))
-	for _, res := range vectorSearchResults {
-		oid, err := mongoUtils.StringToObjectID(res.UserID)
+	var facets *dto.SearchFacets
+	if req.Facets {
+		counts, err := s.qdrantService.CountFacets(ctx, filters, queryVector)
 		if err != nil {
-			s.logger.Warn("Failed to convert userID to ObjectID: " + err.Error())
-			continue
+			s.logger.Warn("Failed to count facets: " + err.Error())
+		} else {
+			resolved := resolveFacets(counts, organization.Semantic)
+			facets = &resolved
 		}
-		if oid == userID {
-			continue
-		}
-		userIDs = append(userIDs, oid)
 	}
 
-	users, err := s.userRepo.GetUsersByIDs(ctx, userIDs)
+	results, err := s.hydrateResults(ctx, claims.OrganizationID, vectorSearchResults, userID)
 	if err != nil {
 		s.logger.Error("Failed to get users by IDs: " + err.Error())
 		lib.ResponseInternalServerError(c, err, "Search failed GetUsersByIDs")
 		return
 	}
 
-	userMap := make(map[string]*model.User, len(users))
-	for i := range users {
-		userMap[users[i].ID.Hex()] = users[i]
+	reranked, err := s.rerankResults(ctx, rerank, filters.Query, *organization, results)
+	if err != nil {
+		s.logger.Warn("Rerank " + string(rerank) + " failed, keeping vector order: " + err.Error())
+		rerank = RerankStrategies.None
+	} else {
+		results = reranked
 	}
+	results = pageResults(results, offset, req.Limit)
 
-	results := make([]dto.SearchResultWithUser, 0, len(vectorSearchResults))
-	for _, res := range vectorSearchResults {
-		if user, ok := userMap[res.UserID]; ok {
-			results = append(results, dto.SearchResultWithUser{
-				Score: res.Score,
-				User:  user,
-			})
-		}
+	page := dto.SearchPage{Offset: offset}
+	if uint64(len(results)) == req.Limit {
+		page.NextCursor = strconv.FormatUint(offset+req.Limit, 10)
 	}
 
-	organization, err := s.orgRepo.GetOrganizationByID(ctx, claims.OrganizationID)
-	if err != nil {
-		s.logger.Error("Failed to get organization: " + err.Error())
-		lib.ResponseInternalServerError(c, err, "Failed to get organization")
-		return
+	alreadyShown := make(map[string]bool, len(shown))
+	for _, id := range shown {
+		alreadyShown[id] = true
 	}
 
 	c.Stream(func(w io.Writer) bool {
-		resultsChan := make(chan dto.SearchResultWithUserAndDescription)
-		clientGone := c.Writer.CloseNotify()
+		if refinement != nil {
+			c.SSEvent("refinement", refinement)
+		}
+		if !extracted.IsEmpty() {
+			c.SSEvent("filters", extracted)
+		}
+		if facets != nil {
+			c.SSEvent("facets", facets)
+		}
 
-		go func() {
-			defer close(resultsChan)
-			var wg sync.WaitGroup
-			for _, res := range results {
-				wg.Add(1)
-				go func(res dto.SearchResultWithUser) {
-					defer wg.Done()
-					timeoutCtx, cancel := context.WithTimeout(ctx, 3*time.Minute)
-					defer cancel()
-					desc, err := s.llm.DescribeUser(timeoutCtx, req.Query, *organization, *res.User)
-					if err != nil {
-						s.logger.Warn("DescribeUser failed: " + err.Error())
-						desc = "Failed to generate reasoning"
-					}
-
-					resultsChan <- dto.SearchResultWithUserAndDescription{
-						SearchResultWithUser: &res,
-						Description:          desc,
-					}
-				}(res)
-			}
-			wg.Wait()
-		}()
+		// Matches go out at once with their scores, assessments follow token by token
+		for _, res := range results {
+			c.SSEvent("result", res)
+		}
+		c.Writer.Flush()
+		// Saved before the assessments, a client leaving mid-stream still sees these users excluded next time
+		s.saveResults(chatObjID, results, rerank, alreadyShown)
+
+		describeCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		events := make(chan searchEvent)
+		go s.describeResults(describeCtx, req.Query, *organization, results, events)
 
-		for res := range resultsChan {
+		clientGone := c.Writer.CloseNotify()
+		for event := range events {
 			select {
 			case <-clientGone:
 				s.logger.Warn("Client disconnected during stream")
 				return false
 			default:
-				c.SSEvent("result", res)
-				go func(result dto.SearchResultWithUserAndDescription) {
-					message := model.Message{
-						Role: "assistant",
-						Content: bson.M{
-							"score":       result.Score,
-							"user":        result.User.ID.Hex(),
-							"description": result.Description,
-						},
-						Timestamp: time.Now(),
-					}
-					s.logger.Info(fmt.Sprintf("[%f] Found user: %s", result.Score, result.User.Name))
-					bgCtx := context.Background()
-					if err := s.chatRepo.AddChatMessages(bgCtx, chatObjID, []model.Message{message}); err != nil {
-						s.logger.Error("Failed to save chat message: " + err.Error() + ". Info chatId: " + chatObjID.Hex())
-					}
-				}(res)
 			}
+
+			c.SSEvent(event.name, event.data)
+			c.Writer.Flush()
+
+			done, ok := event.data.(dto.DescriptionDone)
+			if !ok || done.Assessment == nil || alreadyShown[done.UserID] {
+				continue
+			}
+			go func(userID string, assessment *model.MatchAssessment) {
+				if err := s.chatRepo.SetMessageAssessment(context.Background(), chatObjID, userID, assessment); err != nil {
+					s.logger.Error("Failed to save assessment: " + err.Error() + ". Info chatId: " + chatObjID.Hex())
+				}
+			}(done.UserID, done.Assessment)
+		}
+		if paged {
+			c.SSEvent("page", page)
 		}
 		return false
 	})
 }
 
+// saveResults adds a chat message per suggested user. A page may repeat people of the chat, they keep their first message
+func (s *searchService) saveResults(chatID primitive.ObjectID, results []dto.SearchResultWithUser, rerank RerankStrategy, alreadyShown map[string]bool) {
+	messages := make([]model.Message, 0, len(results))
+	for _, result := range results {
+		if alreadyShown[result.User.ID.Hex()] {
+			continue
+		}
+		content := bson.M{
+			"score":           result.Score,
+			"user":            result.User.ID.Hex(),
+			"rerank_strategy": string(rerank),
+			"highlights":      result.Highlights,
+		}
+		if result.RerankScore != nil {
+			content["rerank_score"] = *result.RerankScore
+		}
+		messages = append(messages, model.Message{
+			Role:      "assistant",
+			Content:   content,
+			Timestamp: time.Now(),
+		})
+		s.logger.Info(fmt.Sprintf("[%f] Found user: %s", result.Score, result.User.Name))
+	}
+	if len(messages) == 0 {
+		return
+	}
+	if err := s.chatRepo.AddChatMessages(context.Background(), chatID, messages); err != nil {
+		s.logger.Error("Failed to save chat messages: " + err.Error() + ". Info chatId: " + chatID.Hex())
+	}
+}
+
+// searchEvent - SSE event of the search stream
+type searchEvent struct {
+	name string
+	data any
+}
+
+// describeResults streams assessments of all results as description_delta events and closes events when
+// every assessment is done. description_done of a user comes after all of its deltas
+func (s *searchService) describeResults(
+	ctx context.Context,
+	query string,
+	org model.Organization,
+	results []dto.SearchResultWithUser,
+	events chan<- searchEvent,
+) {
+	defer close(events)
+	send := func(event searchEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(res *dto.SearchResultWithUser) {
+			defer wg.Done()
+			userID := res.User.ID.Hex()
+			assessment, err := s.llm.StreamDescribeUser(ctx, query, org, *res.User, func(attempt int, delta string) {
+				send(searchEvent{name: "description_delta", data: dto.DescriptionDelta{UserID: userID, Attempt: attempt, Delta: delta}})
+			})
+			done := dto.DescriptionDone{UserID: userID, Assessment: &assessment}
+			if err != nil {
+				s.logger.Warn("DescribeUser failed: " + err.Error())
+				done = dto.DescriptionDone{UserID: userID, Error: "Failed to generate reasoning"}
+			}
+			send(searchEvent{name: "description_done", data: done})
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// searchOffset - cursor wins over page, both address the same ranking
+func searchOffset(req dto.SearchRequest) (uint64, error) {
+	var offset uint64
+	if req.Cursor != "" {
+		parsed, err := strconv.ParseUint(req.Cursor, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cursor %q", req.Cursor)
+		}
+		offset = parsed
+	} else if req.Page > 1 {
+		// Checked before multiplying, a huge page would wrap around
+		if req.Limit > 0 && req.Page-1 > maxSearchOffset/req.Limit {
+			return 0, fmt.Errorf("page %d is deeper than offset %d", req.Page, maxSearchOffset)
+		}
+		offset = (req.Page - 1) * req.Limit
+	}
+	if offset > maxSearchOffset {
+		return 0, fmt.Errorf("offset %d is deeper than %d", offset, maxSearchOffset)
+	}
+	return offset, nil
+}
+
+func pageResults(results []dto.SearchResultWithUser, offset uint64, limit uint64) []dto.SearchResultWithUser {
+	if offset >= uint64(len(results)) {
+		return []dto.SearchResultWithUser{}
+	}
+	results = results[offset:]
+	if uint64(len(results)) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// chatUserIDs returns users already suggested by assistant messages of the chat
+func chatUserIDs(chat *model.Chat) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, message := range chat.Messages {
+		if message.Role != "assistant" {
+			continue
+		}
+		id, ok := message.Content["user"].(string)
+		if !ok || id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SimilarUsers godoc
+//
+//	@Summary		Find people like this colleague
+//	@Description	Recommends users whose descriptions are close to the given user (and optional extra positive users)
+//						and far from optional negative users. Only users of the caller's organization are considered.
+//	@Tags			users
+//	@Security		BearerAuth
+//	@Produce		json
+//	@Param			id			path		string							true	"User ID used as the positive example"
+//	@Param			positive	query		[]string						false	"Additional positive user IDs"
+//	@Param			negative	query		[]string						false	"Negative user IDs"
+//	@Param			teams		query		[]string						false	"Filter users by team ids"
+//	@Param			levels		query		[]string						false	"Filter users by level ids"
+//	@Param			locations	query		[]string						false	"Filter users by location ids"
+//	@Param			limit		query		int								false	"Maximum number of users to return (default 5, max 20)"
+//	@Success		200			{object}	dto.SimilarUsersResponse		"Similar users"
+//	@Failure		400			{object}	map[string]string				"Invalid parameters"
+//	@Failure		401			{object}	dto.UnauthorizedResponse		"Unauthorized"
+//	@Failure		404			{object}	map[string]string				"User not found"
+//	@Failure		500			{object}	map[string]string				"Internal server error"
+//	@Router			/api/v1/users/{id}/similar [get]
+func (s *searchService) SimilarUsers(c *gin.Context) {
+	userClaimsRaw, ok := c.Get(jwtUtils.IdentityKey)
+	if !ok || userClaimsRaw == nil {
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedResponse{Message: "Invalid Claims"})
+		return
+	}
+	claims := userClaimsRaw.(*jwtUtils.UserClaims)
+
+	targetID, err := mongoUtils.StringToObjectID(c.Param("id"))
+	if err != nil {
+		lib.ResponseBadRequest(c, errors.New("wrong user id"), "Wrong id format")
+		return
+	}
+
+	positive, err := parseObjectIDs(queryList(c, "positive"))
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Wrong positive user id format")
+		return
+	}
+	negative, err := parseObjectIDs(queryList(c, "negative"))
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Wrong negative user id format")
+		return
+	}
+	positive = append([]primitive.ObjectID{targetID}, positive...)
+
+	limit, err := queryLimit(c)
+	if err != nil {
+		lib.ResponseBadRequest(c, err, "Invalid limit")
+		return
+	}
+	if limit == 0 {
+		limit = 5
+	} else if limit > 20 {
+		limit = 20
+	}
+
+	ctx := c.Request.Context()
+
+	// Examples must be colleagues too, otherwise their points would leak vectors of another organization
+	examples, err := s.userRepo.GetUsersByIDs(ctx, append(append([]primitive.ObjectID{}, positive...), negative...))
+	if err != nil {
+		lib.ResponseInternalServerError(c, err, "Failed to get users")
+		return
+	}
+	found := make(map[primitive.ObjectID]bool, len(examples))
+	for _, user := range examples {
+		if user.OrganizationID == claims.OrganizationID {
+			found[user.ID] = true
+		}
+	}
+	if !found[targetID] {
+		lib.ResponseNotFound(c, "User not found")
+		return
+	}
+	for _, id := range append(positive[1:], negative...) {
+		if !found[id] {
+			lib.ResponseBadRequest(c, fmt.Errorf("user %s not found", id.Hex()), "Example user not found")
+			return
+		}
+	}
+
+	filters := qdrant.SearchFilters{
+		OrganizationID: claims.OrganizationID.Hex(),
+		Teams:          queryList(c, "teams"),
+		Levels:         queryList(c, "levels"),
+		Locations:      queryList(c, "locations"),
+		Limit:          limit,
+	}
+
+	recommended, err := s.qdrantService.RecommendUsers(ctx, objectIDsToHex(positive), objectIDsToHex(negative), filters)
+	if err != nil {
+		s.logger.Error("Recommend failed: " + err.Error())
+		lib.ResponseInternalServerError(c, err, "Recommend failed vector DB")
+		return
+	}
+
+	results, err := s.hydrateResults(ctx, claims.OrganizationID, recommended, claims.ID)
+	if err != nil {
+		s.logger.Error("Failed to get users by IDs: " + err.Error())
+		lib.ResponseInternalServerError(c, err, "Recommend failed GetUsersByIDs")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SimilarUsersResponse{Results: results})
+}
+
+// hydrateResults loads users of the vector results in result order, skipping the caller and other organizations
+func (s *searchService) hydrateResults(
+	ctx context.Context,
+	organizationID primitive.ObjectID,
+	vectorResults []qdrant.VectorSearchResult,
+	skip primitive.ObjectID,
+) ([]dto.SearchResultWithUser, error) {
+	userIDs := make([]primitive.ObjectID, 0, len(vectorResults))
+	for _, res := range vectorResults {
+		oid, err := mongoUtils.StringToObjectID(res.UserID)
+		if err != nil {
+			s.logger.Warn("Failed to convert userID to ObjectID: " + err.Error())
+			continue
+		}
+		if oid == skip {
+			continue
+		}
+		userIDs = append(userIDs, oid)
+	}
+
+	users, err := s.userRepo.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	userMap := make(map[string]*model.User, len(users))
+	for i := range users {
+		// Points are tenant-scoped, but never hydrate a user of another organization
+		if users[i].OrganizationID != organizationID {
+			s.logger.Warn("Skipping user from another organization: " + users[i].ID.Hex())
+			continue
+		}
+		userMap[users[i].ID.Hex()] = users[i]
+	}
+
+	results := make([]dto.SearchResultWithUser, 0, len(vectorResults))
+	for _, res := range vectorResults {
+		if user, ok := userMap[res.UserID]; ok {
+			highlights := make([]dto.PassageHighlight, 0, len(res.Passages))
+			for _, p := range res.Passages {
+				highlights = append(highlights, dto.PassageHighlight{Text: p.Text, Score: p.Score})
+			}
+			results = append(results, dto.SearchResultWithUser{
+				Score:      res.Score,
+				Highlights: highlights,
+				User:       user,
+			})
+		}
+	}
+	return results, nil
+}
+
+func parseObjectIDs(ids []string) ([]primitive.ObjectID, error) {
+	result := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := mongoUtils.StringToObjectID(id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, oid)
+	}
+	return result, nil
+}
+
+func objectIDsToHex(ids []primitive.ObjectID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id.Hex()
+	}
+	return result
+}
+
 // parseSearchRequest parses search parameters from query string
 // Formats: ?teams=team1,team2 или ?teams[]=team1&teams[]=team2
 func parseSearchRequest(ctx *gin.Context, req *dto.SearchRequest) error {
 	req.Query = ctx.Query("q")
 	req.ChatId = ctx.Query("chatId")
+	req.Mode = ctx.Query("mode")
+	req.ExtractFilters = ctx.DefaultQuery("extract", "true") != "false"
+	req.Facets = ctx.DefaultQuery("facets", "true") != "false"
+	req.Aggregate = ctx.Query("aggregate")
+	req.Rerank = ctx.Query("rerank")
 
-	// Teams
-	if teams := ctx.Query("teams"); teams != "" {
-		req.Teams = strings.Split(teams, ",")
-	} else {
-		req.Teams = ctx.QueryArray("teams[]")
-	}
+	req.Teams = queryList(ctx, "teams")
+	req.Levels = queryList(ctx, "levels")
+	req.Locations = queryList(ctx, "locations")
+	req.ExcludeTeams = queryList(ctx, "excludeTeams")
+	req.ExcludeLevels = queryList(ctx, "excludeLevels")
+	req.ExcludeLocations = queryList(ctx, "excludeLocations")
+	req.ExcludeUsers = queryList(ctx, "excludeUsers")
+	req.NegativeQuery = strings.TrimSpace(ctx.Query("not"))
 
-	// Levels
-	if levels := ctx.Query("levels"); levels != "" {
-		req.Levels = strings.Split(levels, ",")
-	} else {
-		req.Levels = ctx.QueryArray("levels[]")
+	limit, err := queryLimit(ctx)
+	if err != nil {
+		return err
 	}
+	req.Limit = limit
 
-	// Locations
-	if locations := ctx.Query("locations"); locations != "" {
-		req.Locations = strings.Split(locations, ",")
-	} else {
-		req.Locations = ctx.QueryArray("locations[]")
+	if diversityStr := ctx.Query("diversity"); diversityStr != "" {
+		diversity, err := strconv.ParseFloat(diversityStr, 64)
+		if err != nil || diversity < 0 || diversity > 1 {
+			return fmt.Errorf("diversity must be a number in [0, 1]")
+		}
+		req.Diversity = &diversity
+	}
+	if req.MaxPerTeam, err = queryInt(ctx, "maxPerTeam"); err != nil {
+		return err
+	}
+	if req.MaxPerLocation, err = queryInt(ctx, "maxPerLocation"); err != nil {
+		return err
 	}
 
-	// Limit
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		limit, err := strconv.ParseUint(limitStr, 10, 64)
+	req.Cursor = ctx.Query("cursor")
+	if pageStr := ctx.Query("page"); pageStr != "" {
+		page, err := strconv.ParseUint(pageStr, 10, 64)
 		if err != nil {
 			return err
 		}
-		req.Limit = limit
+		req.Page = page
+	}
+
+	return nil
+}
+
+// queryList reads ?key=a,b or ?key[]=a&key[]=b
+func queryList(ctx *gin.Context, key string) []string {
+	var items []string
+	if value := ctx.Query(key); value != "" {
+		items = strings.Split(value, ",")
 	} else {
-		req.Limit = 5
+		items = ctx.QueryArray(key + "[]")
 	}
+	return filterEmpty(items)
+}
 
-	req.Teams = filterEmpty(req.Teams)
-	req.Levels = filterEmpty(req.Levels)
-	req.Locations = filterEmpty(req.Locations)
+// queryInt reads an optional non-negative integer, 0 when absent
+func queryInt(ctx *gin.Context, key string) (int, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return parsed, nil
+}
 
-	return nil
+// queryLimit reads ?limit=, default 5
+func queryLimit(ctx *gin.Context) (uint64, error) {
+	limitStr := ctx.Query("limit")
+	if limitStr == "" {
+		return 5, nil
+	}
+	return strconv.ParseUint(limitStr, 10, 64)
 }
 
 // filterEmpty removes empty strings from slice
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"semki/internal/adapter/mongo"
+	"semki/internal/controller/http/v1/dto"
+	"semki/internal/model"
+	"testing"
+)
+
+func TestChatUserIDs(t *testing.T) {
+	chat := &model.Chat{Messages: []model.Message{
+		{Role: "user", Content: bson.M{"title": "kafka"}},
+		{Role: "assistant", Content: bson.M{"user": "a"}},
+		{Role: "assistant", Content: bson.M{"user": "b"}},
+		{Role: "assistant", Content: bson.M{"user": "a"}},
+	}}
+
+	assert.Equal(t, []string{"a", "b"}, chatUserIDs(chat))
+}
+
+func TestSearchOffset(t *testing.T) {
+	offset, err := searchOffset(dto.SearchRequest{Page: 3, Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), offset)
+
+	offset, err = searchOffset(dto.SearchRequest{Page: 3, Cursor: "7", Limit: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), offset)
+
+	_, err = searchOffset(dto.SearchRequest{Cursor: "abc", Limit: 5})
+	assert.Error(t, err)
+
+	_, err = searchOffset(dto.SearchRequest{Page: 1000, Limit: 20})
+	assert.Error(t, err)
+
+	_, err = searchOffset(dto.SearchRequest{Page: 1<<63 + 1, Limit: 2})
+	assert.Error(t, err, "(page-1)*limit wraps around to 0")
+}
+
+func TestPageResults(t *testing.T) {
+	results := make([]dto.SearchResultWithUser, 7)
+
+	assert.Len(t, pageResults(results, 5, 5), 2)
+	assert.Len(t, pageResults(results, 0, 5), 5)
+	assert.Empty(t, pageResults(results, 10, 5))
+}
+
+func TestDescribeResultsStreamsDeltasBeforeDone(t *testing.T) {
+	s := &searchService{llm: NewFakeLLMService(), logger: zap.NewNop()}
+	results := []dto.SearchResultWithUser{
+		{Score: 0.9, User: &model.User{ID: primitive.NewObjectID(), Name: "Ann", Semantic: model.UserSemantic{Description: "Go developer"}}},
+		{Score: 0.8, User: &model.User{ID: primitive.NewObjectID(), Name: "Bob", Semantic: model.UserSemantic{Description: "Go and Rust"}}},
+	}
+
+	events := make(chan searchEvent)
+	go s.describeResults(context.Background(), "go developer", model.Organization{}, results, events)
+
+	streamed := make(map[string]string)
+	done := make(map[string]*model.MatchAssessment)
+	for event := range events {
+		switch data := event.data.(type) {
+		case dto.DescriptionDelta:
+			assert.NotContains(t, done, data.UserID, "no deltas after done")
+			streamed[data.UserID] += data.Delta
+		case dto.DescriptionDone:
+			done[data.UserID] = data.Assessment
+		}
+	}
+
+	assert.Len(t, done, 2)
+	for userID, assessment := range done {
+		var parsed model.MatchAssessment
+		assert.NoError(t, json.Unmarshal([]byte(streamed[userID]), &parsed))
+		assert.Equal(t, *assessment, parsed)
+	}
+}
+
+func TestDescribeResultsStopsWhenCancelled(t *testing.T) {
+	s := &searchService{llm: NewFakeLLMService(), logger: zap.NewNop()}
+	results := []dto.SearchResultWithUser{{User: &model.User{ID: primitive.NewObjectID(), Semantic: model.UserSemantic{Description: "Go"}}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan searchEvent)
+	go s.describeResults(ctx, "go", model.Organization{}, results, events)
+	<-events
+	cancel()
+
+	// Cancelled explanations still end with description_done and the channel is closed
+	for range events {
+	}
+}
+
+type savedChat struct {
+	mongo.IChatRepository
+	messages []model.Message
+}
+
+func (r *savedChat) AddChatMessages(_ context.Context, _ primitive.ObjectID, messages []model.Message) error {
+	r.messages = append(r.messages, messages...)
+	return nil
+}
+
+func TestSaveResultsSkipsUsersAlreadyShown(t *testing.T) {
+	chat := &savedChat{}
+	s := &searchService{chatRepo: chat, logger: zap.NewNop()}
+	shown := primitive.NewObjectID()
+	fresh := primitive.NewObjectID()
+	results := []dto.SearchResultWithUser{
+		{Score: 0.9, User: &model.User{ID: shown}},
+		{Score: 0.8, User: &model.User{ID: fresh}},
+	}
+
+	s.saveResults(primitive.NewObjectID(), results, RerankStrategies.None, map[string]bool{shown.Hex(): true})
+
+	assert.Len(t, chat.messages, 1)
+	assert.Equal(t, fresh.Hex(), chat.messages[0].Content["user"])
+	assert.Nil(t, chat.messages[0].Assessment)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	ginJwt "github.com/appleboy/gin-jwt/v3"
 	"github.com/gin-gonic/gin"
@@ -24,16 +25,16 @@ import (
 
 // userService - dependent services
 type userService struct {
-	qdrantService IQdrantService
-	userRepo      mongo.IUserRepository
-	orgRepo       mongo.IOrganizationRepository
-	emailService  *EmailService
-	jwtAuth       *ginJwt.GinJWTMiddleware
-	cfg           *config.Config
+	outbox       IOutboxService
+	userRepo     mongo.IUserRepository
+	orgRepo      mongo.IOrganizationRepository
+	emailService *EmailService
+	jwtAuth      *ginJwt.GinJWTMiddleware
+	cfg          *config.Config
 }
 
-func NewUserService(qdrantService IQdrantService, userRepo mongo.IUserRepository, orgRepo mongo.IOrganizationRepository, emailService *EmailService, jwtAuth *ginJwt.GinJWTMiddleware, cfg *config.Config) routes.IUserService {
-	return &userService{qdrantService, userRepo, orgRepo, emailService, jwtAuth, cfg}
+func NewUserService(outbox IOutboxService, userRepo mongo.IUserRepository, orgRepo mongo.IOrganizationRepository, emailService *EmailService, jwtAuth *ginJwt.GinJWTMiddleware, cfg *config.Config) routes.IUserService {
+	return &userService{outbox, userRepo, orgRepo, emailService, jwtAuth, cfg}
 }
 
 // CreateUser godoc
@@ -91,15 +92,14 @@ func (s *userService) CreateUser(c *gin.Context) {
 	// Creating user
 	user := dto.NewUserFromRequest(userDto)
 
-	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+	err = s.outbox.Write(ctx, user.ID, func(ctx context.Context) error {
+		return s.userRepo.CreateUser(ctx, user)
+	})
+	if err != nil {
 		lib.ResponseInternalServerError(c, err, "Failed to create user")
 		return
 	}
 
-	if err := s.qdrantService.IndexUser(ctx, user); err != nil {
-		telemetry.Log.Error("Failed to index user in Qdrant: " + err.Error())
-	}
-
 	c.JSON(http.StatusCreated, dto.CreateUserResponse{Message: "User created", User: *user})
 }
 
@@ -183,7 +183,10 @@ func (s *userService) RegisterUser(c *gin.Context) {
 	user.OrganizationID = organization.ID
 	user.OrganizationRole = model.OrganizationRoles.OWNER
 
-	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+	err = s.outbox.Write(ctx, user.ID, func(ctx context.Context) error {
+		return s.userRepo.CreateUser(ctx, user)
+	})
+	if err != nil {
 		lib.ResponseInternalServerError(c, err, "Failed to create user")
 		return
 	}
@@ -207,10 +210,6 @@ func (s *userService) RegisterUser(c *gin.Context) {
 		return
 	}
 
-	if err := s.qdrantService.IndexUser(ctx, user); err != nil {
-		telemetry.Log.Error("Failed to index created user in Qdrant: " + err.Error())
-	}
-
 	// Token
 	jwtToken, err := s.jwtAuth.TokenGenerator(user)
 	if err != nil {
@@ -385,15 +384,14 @@ func (s *userService) UpdateUser(c *gin.Context) {
 		user.Password = userByID.Password
 	}
 
-	if err := s.userRepo.UpdateUser(ctx, paramObjectId, user); err != nil {
+	err = s.outbox.Write(ctx, paramObjectId, func(ctx context.Context) error {
+		return s.userRepo.UpdateUser(ctx, paramObjectId, user)
+	})
+	if err != nil {
 		lib.ResponseInternalServerError(c, err, "Failed to update user")
 		return
 	}
 
-	if err := s.qdrantService.UpdateUser(ctx, &user); err != nil {
-		telemetry.Log.Error("Failed to update user in Qdrant: " + err.Error())
-	}
-
 	c.JSON(http.StatusOK, dto.UpdateUserResponse{Message: "User updated"})
 }
 
@@ -515,15 +513,14 @@ func (s *userService) PatchUser(c *gin.Context) {
 		return
 	}
 
-	if err := s.userRepo.PatchUser(ctx, paramObjectId, update); err != nil {
+	err = s.outbox.Write(ctx, paramObjectId, func(ctx context.Context) error {
+		return s.userRepo.PatchUser(ctx, paramObjectId, update)
+	})
+	if err != nil {
 		lib.ResponseInternalServerError(c, err, "Failed to patch user")
 		return
 	}
 
-	if err := s.qdrantService.UpdateUser(ctx, existing); err != nil {
-		telemetry.Log.Error("Failed to update user in Qdrant: " + err.Error())
-	}
-
 	c.JSON(http.StatusOK, dto.UpdateUserResponse{Message: "User patched"})
 }
 
@@ -564,15 +561,14 @@ func (s *userService) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := s.userRepo.DeleteUser(ctx, paramObjectId); err != nil {
+	err = s.outbox.Write(ctx, paramObjectId, func(ctx context.Context) error {
+		return s.userRepo.DeleteUser(ctx, paramObjectId)
+	})
+	if err != nil {
 		lib.ResponseInternalServerError(c, err, "Failed to delete user")
 		return
 	}
 
-	if err := s.qdrantService.DeleteUser(ctx, id); err != nil {
-		telemetry.Log.Error("Failed to delete user in Qdrant: " + err.Error())
-	}
-
 	c.JSON(http.StatusOK, dto.DeleteUserResponse{Message: "User deleted"})
 }
 
@@ -597,21 +593,14 @@ func (s *userService) RestoreUser(c *gin.Context) {
 		return
 	}
 
-	if err := s.userRepo.RestoreUser(ctx, paramObjectId); err != nil {
+	err = s.outbox.Write(ctx, paramObjectId, func(ctx context.Context) error {
+		return s.userRepo.RestoreUser(ctx, paramObjectId)
+	})
+	if err != nil {
 		lib.ResponseInternalServerError(c, err, "Failed to restore user")
 		return
 	}
 
-	user, err := s.userRepo.GetUserByID(ctx, paramObjectId)
-	if err != nil || user == nil {
-		lib.ResponseInternalServerError(c, err, "Failed to fetch restored user")
-		return
-	}
-
-	if err := s.qdrantService.IndexUser(ctx, user); err != nil {
-		telemetry.Log.Error("Failed to re-index user in Qdrant: " + err.Error())
-	}
-
 	c.JSON(http.StatusOK, dto.DeleteUserResponse{Message: "User deleted"})
 }
 
@@ -723,17 +712,15 @@ func (s *userService) InviteUserAcceptHandler(c *gin.Context) {
 	if user.Status == model.UserStatuses.INVITED {
 		user.Status = model.UserStatuses.ACTIVE
 		user.Verified = true
-		err = s.userRepo.UpdateUser(ctx, user.ID, *user)
+		err = s.outbox.Write(ctx, user.ID, func(ctx context.Context) error {
+			return s.userRepo.UpdateUser(ctx, user.ID, *user)
+		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, lib.ErrorResponse{Message: err.Error()})
 			return
 		}
 	}
 
-	if err := s.qdrantService.IndexUser(ctx, user); err != nil {
-		telemetry.Log.Error("Failed to index invited user in Qdrant: " + err.Error())
-	}
-
 	// Token
 	jwtToken, err := s.jwtAuth.TokenGenerator(user)
 	if err != nil {
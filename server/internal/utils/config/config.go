@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // AppConfig - app.yml for service const values
@@ -33,13 +34,63 @@ type QdrantConfig struct {
 	Host     string
 	HttpPort int
 	GrpcPort int
+	// KeepVersions - collection versions kept for rollback, the live one included
+	KeepVersions int
 }
 
 type EmbedderConfig struct {
-	Url        string
+	// Provider - http for the self-hosted embedder, openai for a /v1/embeddings API, hashing for offline tests
+	Provider string
+	Url      string
+	// BaseURL and APIKey of the OpenAI-compatible provider
+	BaseURL    string
+	APIKey     string
 	Host       string
 	Port       int
 	Dimensions int
+	// Model - id of the embedding model, cached vectors of other models are dropped
+	Model string
+	// ModelVersion - bumped when the model behind the same id changes, e.g. new weights
+	ModelVersion string
+	// CacheTTL - lifetime of cached embeddings in Redis, 0 disables the cache
+	CacheTTL time.Duration
+	// BatchLinger - how long concurrent calls are collected into one request, 0 disables batching
+	BatchLinger time.Duration
+	MaxBatch    int
+	// Timeout - limit of a single request to the embedder, retries get a fresh one
+	Timeout    time.Duration
+	MaxRetries int
+	RetryBase  time.Duration
+	// BreakerFailures - consecutive failures that open the circuit, 0 disables the breaker
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+// SearchConfig - final score = weighted user description, team guidance and level guidance similarity
+type SearchConfig struct {
+	UserWeight  float64
+	TeamWeight  float64
+	LevelWeight float64
+	// Rerank - default reranking strategy: none, cross_encoder or llm
+	Rerank           string
+	RerankCandidates int
+	// DiversityLambda - default MMR lambda, 0 disables diversity re-ordering
+	DiversityLambda float64
+	// FacetThreshold - minimal similarity of a candidate counted in facets, FacetCandidates - how many are looked at
+	FacetThreshold  float64
+	FacetCandidates int
+	// PassageAggregation - none, max or sum, PassageWords - maximal passage length of a description
+	PassageAggregation string
+	PassageWords       int
+}
+
+// OutboxConfig - Qdrant sync worker. The retry delay doubles from RetryBase up to RetryMax,
+// after MaxAttempts the event is marked as failed
+type OutboxConfig struct {
+	PollInterval time.Duration
+	MaxAttempts  int
+	RetryBase    time.Duration
+	RetryMax     time.Duration
 }
 
 type MongoConfig struct {
@@ -62,6 +113,20 @@ type RedisConfig struct {
 	Password string
 }
 
+// LLMConfig - chat model for descriptions, reranking and query rewriting
+type LLMConfig struct {
+	// Provider - openai, compatible for any OpenAI-compatible BaseURL (vLLM, Ollama) or fake for offline tests
+	Provider string
+	BaseURL  string
+	// APIKey - falls back to OPEN_AI_KEY
+	APIKey string
+	// Model - default model, organizations may pick another one of AllowedModels
+	Model         string
+	AllowedModels []string
+	// Timeout - limit of a single completion
+	Timeout time.Duration
+}
+
 type SMTPConfig struct {
 	Host     string
 	Port     int
@@ -74,23 +139,27 @@ type SMTPConfig struct {
 // Config - app.yml + .env for secrets & dev/prod values
 type Config struct {
 	*AppConfig
-	Environment      string
-	SecretKeyJWT     string
-	CryptoKey        string
-	IsDebug          bool
-	Mongo            MongoConfig
-	Google           GoogleConfig
-	Jaeger           JaegerConfig
-	Qdrant           QdrantConfig
-	Embedder         EmbedderConfig
-	Redis            RedisConfig
-	SMTP             SMTPConfig
-	PyroscopeAddress string
-	FrontendUrl      string
-	JsonLog          bool
-	EnabledPyroscope bool
-	EnabledSentry    bool
-	OpenAIKey        string
+	Environment  string
+	SecretKeyJWT string
+	CryptoKey    string
+	// PlatformOperators - user ids allowed to run operations affecting every organization, e.g. index rollback
+	PlatformOperators []string
+	IsDebug           bool
+	Mongo             MongoConfig
+	Google            GoogleConfig
+	Jaeger            JaegerConfig
+	Qdrant            QdrantConfig
+	Embedder          EmbedderConfig
+	Search            SearchConfig
+	Outbox            OutboxConfig
+	LLM               LLMConfig
+	Redis             RedisConfig
+	SMTP              SMTPConfig
+	PyroscopeAddress  string
+	FrontendUrl       string
+	JsonLog           bool
+	EnabledPyroscope  bool
+	EnabledSentry     bool
 }
 
 const configPath = "app.yml"
@@ -156,6 +225,7 @@ func GetConfig(rootPath string) *Config {
 		instance.Qdrant.Host = getEnvKey("QDRANT_HOST")
 		instance.Qdrant.GrpcPort = getEnvKeyInt("QDRANT_GRPC_PORT")
 		instance.Qdrant.HttpPort = getEnvKeyInt("QDRANT_HTTP_PORT")
+		instance.Qdrant.KeepVersions = getEnvKeyIntOrDefault("QDRANT_KEEP_VERSIONS", 3)
 
 		instance.Redis.Host = getEnvKey("REDIS_HOST")
 		instance.Redis.Password = getEnvKey("REDIS_PASSWORD")
@@ -164,7 +234,36 @@ func GetConfig(rootPath string) *Config {
 		instance.Embedder.Host = getEnvKey("EMBEDDER_HOST")
 		instance.Embedder.Port = getEnvKeyInt("EMBEDDER_PORT")
 		instance.Embedder.Url = fmt.Sprintf("http://%s:%d", instance.Embedder.Host, instance.Embedder.Port)
+		instance.Embedder.Provider = getEnvKeyOrDefault("EMBEDDER_PROVIDER", "http")
+		instance.Embedder.BaseURL = getEnvKeyOrDefault("EMBEDDER_BASE_URL", "https://api.openai.com")
+		instance.Embedder.APIKey = getEnvKeyOrDefault("EMBEDDER_API_KEY", "")
 		instance.Embedder.Dimensions = getEnvKeyInt("EMBEDDER_DIMENSIONS")
+		instance.Embedder.Model = getEnvKeyOrDefault("EMBEDDER_MODEL", "default")
+		instance.Embedder.ModelVersion = getEnvKeyOrDefault("EMBEDDER_MODEL_VERSION", "1")
+		instance.Embedder.CacheTTL = time.Duration(getEnvKeyIntOrDefault("EMBEDDER_CACHE_TTL_HOURS", 168)) * time.Hour
+		instance.Embedder.BatchLinger = time.Duration(getEnvKeyIntOrDefault("EMBEDDER_BATCH_LINGER_MS", 5)) * time.Millisecond
+		instance.Embedder.MaxBatch = getEnvKeyIntOrDefault("EMBEDDER_MAX_BATCH", 32)
+		instance.Embedder.Timeout = time.Duration(getEnvKeyIntOrDefault("EMBEDDER_TIMEOUT_SECONDS", 30)) * time.Second
+		instance.Embedder.MaxRetries = getEnvKeyIntOrDefault("EMBEDDER_MAX_RETRIES", 2)
+		instance.Embedder.RetryBase = time.Duration(getEnvKeyIntOrDefault("EMBEDDER_RETRY_BASE_MS", 200)) * time.Millisecond
+		instance.Embedder.BreakerFailures = getEnvKeyIntOrDefault("EMBEDDER_BREAKER_FAILURES", 5)
+		instance.Embedder.BreakerCooldown = time.Duration(getEnvKeyIntOrDefault("EMBEDDER_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second
+
+		instance.Search.UserWeight = getEnvKeyFloatOrDefault("SEARCH_USER_WEIGHT", 0.7)
+		instance.Search.TeamWeight = getEnvKeyFloatOrDefault("SEARCH_TEAM_WEIGHT", 0.2)
+		instance.Search.LevelWeight = getEnvKeyFloatOrDefault("SEARCH_LEVEL_WEIGHT", 0.1)
+		instance.Search.Rerank = getEnvKeyOrDefault("SEARCH_RERANK", "none")
+		instance.Search.RerankCandidates = getEnvKeyIntOrDefault("SEARCH_RERANK_CANDIDATES", 50)
+		instance.Search.DiversityLambda = getEnvKeyFloatOrDefault("SEARCH_DIVERSITY_LAMBDA", 0)
+		instance.Search.FacetThreshold = getEnvKeyFloatOrDefault("SEARCH_FACET_THRESHOLD", 0.3)
+		instance.Search.FacetCandidates = getEnvKeyIntOrDefault("SEARCH_FACET_CANDIDATES", 200)
+		instance.Search.PassageAggregation = getEnvKeyOrDefault("SEARCH_PASSAGE_AGGREGATION", "none")
+		instance.Search.PassageWords = getEnvKeyIntOrDefault("SEARCH_PASSAGE_WORDS", 60)
+
+		instance.Outbox.PollInterval = time.Duration(getEnvKeyIntOrDefault("OUTBOX_POLL_INTERVAL_MS", 1000)) * time.Millisecond
+		instance.Outbox.MaxAttempts = getEnvKeyIntOrDefault("OUTBOX_MAX_ATTEMPTS", 10)
+		instance.Outbox.RetryBase = time.Duration(getEnvKeyIntOrDefault("OUTBOX_RETRY_BASE_MS", 1000)) * time.Millisecond
+		instance.Outbox.RetryMax = time.Duration(getEnvKeyIntOrDefault("OUTBOX_RETRY_MAX_MS", 300000)) * time.Millisecond
 
 		instance.FrontendUrl = getEnvKey("FRONTEND_URL")
 		instance.JsonLog = getEnvKey("JSON_LOG") == "true"
@@ -179,7 +278,13 @@ func GetConfig(rootPath string) *Config {
 		instance.SMTP.From = getEnvKey("SMTP_FROM")
 		instance.SMTP.FromName = getEnvKey("SMTP_FROM_NAME")
 
-		instance.OpenAIKey = getEnvKey("OPEN_AI_KEY")
+		instance.LLM.Provider = getEnvKeyOrDefault("LLM_PROVIDER", "openai")
+		instance.LLM.BaseURL = getEnvKeyOrDefault("LLM_BASE_URL", "")
+		instance.LLM.APIKey = getEnvKeyOrDefault("LLM_API_KEY", getEnvKeyOrDefault("OPEN_AI_KEY", ""))
+		instance.LLM.Model = getEnvKeyOrDefault("LLM_MODEL", "gpt-5-mini")
+		instance.PlatformOperators = strings.FieldsFunc(getEnvKeyOrDefault("PLATFORM_OPERATOR_IDS", ""), func(r rune) bool { return r == ',' || r == ' ' })
+		instance.LLM.AllowedModels = strings.FieldsFunc(getEnvKeyOrDefault("LLM_ALLOWED_MODELS", ""), func(r rune) bool { return r == ',' || r == ' ' })
+		instance.LLM.Timeout = time.Duration(getEnvKeyIntOrDefault("LLM_TIMEOUT_SECONDS", 120)) * time.Second
 		// endregion
 	})
 	return instance
@@ -206,3 +311,38 @@ func getEnvKeyInt(key string) int {
 
 	return num
 }
+
+func getEnvKeyOrDefault(key string, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvKeyIntOrDefault(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("[getEnvKeyIntOrDefault] cannot convert to int: %s", value)
+	}
+
+	return num
+}
+
+func getEnvKeyFloatOrDefault(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	num, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("[getEnvKeyFloatOrDefault] cannot convert to float: %s", value)
+	}
+
+	return num
+}
@@ -17,6 +17,7 @@ import (
 	"semki/internal/utils/config"
 	"semki/pkg/lib"
 	"semki/pkg/telemetry"
+	"slices"
 	"strings"
 	"time"
 )
@@ -37,7 +38,7 @@ func Startup(cfg *config.Config, service routes.IAuthService) *jwt.GinJWTMiddlew
 		IdentityKey:     IdentityKey,
 		IdentityHandler: identity,
 		Authenticator:   authenticator(service),
-		Authorizer:      authorization,
+		Authorizer:      authorization(cfg.PlatformOperators),
 		Unauthorized:    unauthorized,
 		PayloadFunc:     payloadFunc,
 		TokenLookup:     fmt.Sprintf("header:%s", AuthorizationHeader),
@@ -173,12 +174,31 @@ func identity(c *gin.Context) interface{} {
 
 // region Authorization
 
-func authorization(c *gin.Context, data any) bool {
-	user, ok := data.(*UserClaims)
-	if !ok || user == nil {
-		return false
+// operatorRoutes affect every organization, organization admins can't use them
+var operatorRoutes = map[string]map[string]struct{}{
+	"POST": {
+		"/api/v1/reindex/versions/:version/activate": {},
+		"/api/v1/reindex/models/confirm":             {},
+	},
+	"GET": {
+		"/api/v1/reindex/versions": {},
+	},
+}
+
+func authorization(operators []string) func(c *gin.Context, data any) bool {
+	return func(c *gin.Context, data any) bool {
+		user, ok := data.(*UserClaims)
+		if !ok || user == nil {
+			return false
+		}
+		if _, found := operatorRoutes[c.Request.Method][c.FullPath()]; found {
+			return slices.Contains(operators, user.ID.Hex())
+		}
+		return authorizeRole(c, user)
 	}
+}
 
+func authorizeRole(c *gin.Context, user *UserClaims) bool {
 	if user.OrganizationRole == model.OrganizationRoles.ADMIN || user.OrganizationRole == model.OrganizationRoles.OWNER {
 		return true
 	}
@@ -191,9 +211,16 @@ func authorization(c *gin.Context, data any) bool {
 			"/api/v1/organization/levels":      {},
 			"/api/v1/organization/locations":   {},
 			"/api/v1/reindex":                  {},
+			"/api/v1/reindex/drift/repair":     {},
 			"/api/v1/organization/insert-mock": {},
 		},
+		"GET": {
+			"/api/v1/reindex/:jobId": {},
+			"/api/v1/reindex/drift":  {},
+			"/api/v1/reindex/models": {},
+		},
 		"DELETE": {
+			"/api/v1/reindex/:jobId":                     {},
 			"/api/v1/user/:id":                           {},
 			"/api/v1/organization":                       {},
 			"/api/v1/organization/teams/:teamId":         {},
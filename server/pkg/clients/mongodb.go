@@ -17,6 +17,7 @@ type MongoCollectionsNamesType struct {
 	Levels        string
 	Users         string
 	Chats         string
+	Outbox        string
 }
 
 var MongoCollectionsNames = MongoCollectionsNamesType{
@@ -25,6 +26,7 @@ var MongoCollectionsNames = MongoCollectionsNamesType{
 	Levels:        "levels",
 	Users:         "users",
 	Chats:         "chats",
+	Outbox:        "outbox",
 }
 
 // endregion
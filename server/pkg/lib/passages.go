@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	paragraphSplit = regexp.MustCompile(`\n\s*\n`)
+	sentenceMatch  = regexp.MustCompile(`[^.!?\n]+[.!?]*`)
+)
+
+// SplitPassages splits a description into passages of at most maxWords words.
+// Sentences are kept whole and never glued across paragraphs, only a sentence longer than maxWords is cut
+func SplitPassages(text string, maxWords int) []string {
+	if maxWords <= 0 {
+		maxWords = 1
+	}
+
+	var passages []string
+	for _, paragraph := range paragraphSplit.Split(text, -1) {
+		var current []string
+		flush := func() {
+			if len(current) > 0 {
+				passages = append(passages, strings.Join(current, " "))
+				current = nil
+			}
+		}
+
+		for _, sentence := range sentenceMatch.FindAllString(paragraph, -1) {
+			words := strings.Fields(sentence)
+			if len(words) == 0 {
+				continue
+			}
+			if len(current)+len(words) > maxWords {
+				flush()
+			}
+			for len(words) > maxWords {
+				passages = append(passages, strings.Join(words[:maxWords], " "))
+				words = words[maxWords:]
+			}
+			current = append(current, words...)
+		}
+		flush()
+	}
+
+	return passages
+}
@@ -0,0 +1,26 @@
+package lib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplitPassages(t *testing.T) {
+	text := "I run the Kafka cluster. Ask me about partitions!\n\nI also mentor juniors on Go."
+
+	assert.Equal(t, []string{
+		"I run the Kafka cluster. Ask me about partitions!",
+		"I also mentor juniors on Go.",
+	}, SplitPassages(text, 20))
+
+	assert.Equal(t, []string{
+		"I run the Kafka cluster.",
+		"Ask me about partitions!",
+		"I also mentor juniors on Go.",
+	}, SplitPassages(text, 6))
+}
+
+func TestSplitPassagesCutsLongSentences(t *testing.T) {
+	assert.Equal(t, []string{"a b", "c d", "e"}, SplitPassages("a b c d e", 2))
+	assert.Empty(t, SplitPassages("  ", 10))
+}
@@ -0,0 +1,85 @@
+package lib
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 term frequency saturation. IDF is applied by Qdrant (Modifier_Idf) on the collection side
+const (
+	bm25K1          = 1.2
+	bm25B           = 0.75
+	bm25AvgDocWords = 64
+)
+
+// SparseVector - hashed terms with their weights, ready for a Qdrant sparse vector
+type SparseVector struct {
+	Indices []uint32
+	Values  []float32
+}
+
+// Tokenize lowercases text and splits it into words. Exact tokens matter here:
+// "SOC2", "PCI", "k8s" must survive as-is to be matched lexically
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// SparseDocumentVector encodes an indexed text with BM25 term frequencies
+func SparseDocumentVector(text string) SparseVector {
+	tokens := Tokenize(text)
+	docLen := float64(len(tokens))
+
+	frequencies := termFrequencies(tokens)
+	weights := make(map[uint32]float32, len(frequencies))
+	for index, tf := range frequencies {
+		norm := tf + bm25K1*(1-bm25B+bm25B*docLen/bm25AvgDocWords)
+		weights[index] = float32(tf * (bm25K1 + 1) / norm)
+	}
+
+	return newSparseVector(weights)
+}
+
+// SparseQueryVector encodes a search query: every distinct term has the same weight
+func SparseQueryVector(text string) SparseVector {
+	frequencies := termFrequencies(Tokenize(text))
+	weights := make(map[uint32]float32, len(frequencies))
+	for index := range frequencies {
+		weights[index] = 1
+	}
+
+	return newSparseVector(weights)
+}
+
+func termFrequencies(tokens []string) map[uint32]float64 {
+	frequencies := make(map[uint32]float64, len(tokens))
+	for _, token := range tokens {
+		frequencies[termIndex(token)]++
+	}
+	return frequencies
+}
+
+func termIndex(token string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(token))
+	return h.Sum32()
+}
+
+// newSparseVector - Qdrant requires unique indices, sorted for deterministic payloads
+func newSparseVector(weights map[uint32]float32) SparseVector {
+	indices := make([]uint32, 0, len(weights))
+	for index := range weights {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	values := make([]float32, len(indices))
+	for i, index := range indices {
+		values[i] = weights[index]
+	}
+
+	return SparseVector{Indices: indices, Values: values}
+}
@@ -0,0 +1,32 @@
+package lib
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"kafka", "soc2", "and", "pci", "dss"}, Tokenize("Kafka, SOC2 and PCI-DSS"))
+}
+
+func TestSparseDocumentVector(t *testing.T) {
+	vector := SparseDocumentVector("kafka kafka streams")
+	assert.Len(t, vector.Indices, 2)
+	assert.Len(t, vector.Values, 2)
+
+	weights := map[uint32]float32{}
+	for i, index := range vector.Indices {
+		weights[index] = vector.Values[i]
+	}
+	assert.Greater(t, weights[termIndex("kafka")], weights[termIndex("streams")])
+}
+
+func TestSparseQueryVectorMatchesDocumentTerms(t *testing.T) {
+	query := SparseQueryVector("Who knows Kafka?")
+	document := SparseDocumentVector("Runs our kafka clusters")
+	assert.Contains(t, query.Indices, termIndex("kafka"))
+	assert.Contains(t, document.Indices, termIndex("kafka"))
+	for _, value := range query.Values {
+		assert.Equal(t, float32(1), value)
+	}
+}